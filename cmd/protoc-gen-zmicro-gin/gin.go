@@ -1,10 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"google.golang.org/genproto/googleapis/api/annotations"
 	"google.golang.org/protobuf/compiler/protogen"
@@ -21,6 +23,7 @@ var (
 	contextPackage       = protogen.GoImportPath("context")
 	ginPackage           = protogen.GoImportPath("github.com/gin-gonic/gin")
 	netHttpPackage       = protogen.GoImportPath("net/http")
+	timePackage          = protogen.GoImportPath("time")
 	transportHttpPackage = protogen.GoImportPath("github.com/zmicro-team/zmicro/core/transport/http")
 )
 
@@ -28,6 +31,9 @@ var methodSets = make(map[string]int)
 
 func runProtoGen(gen *protogen.Plugin) error {
 	gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+	if args.Timeout < 0 {
+		return fmt.Errorf("invalid timeout %v: negative timeout", args.Timeout)
+	}
 	for _, f := range gen.Files {
 		if !f.Generate {
 			continue
@@ -256,7 +262,8 @@ func buildMethodDesc(g *protogen.GeneratedFile, m *protogen.Method, method, path
 			}
 		}
 	}
-	comment := m.Comments.Leading.String() + m.Comments.Trailing.String()
+	timeout, leading := parseTimeout(m.Comments.Leading.String())
+	comment := leading + m.Comments.Trailing.String()
 	if comment != "" {
 		comment = strings.TrimSpace(strings.TrimPrefix(strings.TrimSuffix(comment, "\n"), "//")) // nolint
 		comment = "// " + m.GoName + " " + comment
@@ -273,7 +280,37 @@ func buildMethodDesc(g *protogen.GeneratedFile, m *protogen.Method, method, path
 		Method:     method,
 		Comment:    comment,
 		HasVars:    len(vars) > 0,
+		Timeout:    timeout,
+	}
+}
+
+// timeoutDirective sets the timeout of a method in its leading comment,
+// e.g. // zmicro:timeout=3s
+const timeoutDirective = "zmicro:timeout="
+
+// parseTimeout returns the timeout of the leading comment of a method,
+// default the timeout flag, and the comment without the directive.
+func parseTimeout(comment string) (time.Duration, string) {
+	timeout := args.Timeout
+	lines := strings.SplitAfter(comment, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		v, ok := strings.CutPrefix(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "//")), timeoutDirective)
+		if !ok {
+			kept = append(kept, line)
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err == nil && d < 0 {
+			err = errors.New("negative timeout")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\u001B[31mERROR\u001B[m: invalid %s%s: %v\n", timeoutDirective, v, err)
+			os.Exit(2) // nolint: gocritic
+		}
+		timeout = d
 	}
+	return timeout, strings.Join(kept, "")
 }
 
 // transformPathParams 路由路由 {xx} --> :xx
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// timeoutRequest returns a request for a service whose methods set their
+// timeouts with the directive, or get the one of the timeout flag.
+func timeoutRequest(parameter string) *pluginpb.CodeGeneratorRequest {
+	httpRule := func(rule *annotations.HttpRule) *descriptorpb.MethodOptions {
+		opts := &descriptorpb.MethodOptions{}
+		proto.SetExtension(opts, annotations.E_Http, rule)
+		return opts
+	}
+	method := func(name string, rule *annotations.HttpRule) *descriptorpb.MethodDescriptorProto {
+		return &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(".api.HelloRequest"),
+			OutputType: proto.String(".api.HelloReply"),
+			Options:    httpRule(rule),
+		}
+	}
+	comment := func(i int32, s string) *descriptorpb.SourceCodeInfo_Location {
+		// service 0, method i
+		return &descriptorpb.SourceCodeInfo_Location{
+			Path:            []int32{6, 0, 2, i},
+			Span:            []int32{0, 0, 0},
+			LeadingComments: proto.String(s),
+		}
+	}
+	message := func(name, field string) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{
+			Name: proto.String(name),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String(field),
+				JsonName: proto.String(field),
+				Number:   proto.Int32(1),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			}},
+		}
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("api/hello.proto"),
+		Package:    proto.String("api"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/api/annotations.proto"},
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("example.com/api")},
+		MessageType: []*descriptorpb.DescriptorProto{
+			message("HelloRequest", "name"),
+			message("HelloReply", "message"),
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Greeter"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("SayHello", &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/hello/{name}"}}),
+				method("SayFast", &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/fast"}, Body: "*"}),
+				method("SayNow", &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/now"}, Body: "*"}),
+				method("SayDefault", &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/default"}, Body: "*"}),
+			},
+		}},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+			Location: []*descriptorpb.SourceCodeInfo_Location{
+				comment(0, " SayHello says hello.\n zmicro:timeout=3s\n"),
+				comment(1, " zmicro:timeout=1500ms\n"),
+				comment(2, " zmicro:timeout=500us\n"),
+			},
+		},
+	}
+
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{file.GetName()},
+		Parameter:      proto.String(parameter),
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+			protodesc.ToFileDescriptorProto(annotations.File_google_api_http_proto),
+			protodesc.ToFileDescriptorProto(annotations.File_google_api_annotations_proto),
+			file,
+		},
+	}
+}
+
+func TestTimeoutGolden(t *testing.T) {
+	defer func(timeout time.Duration) { args.Timeout = timeout }(args.Timeout)
+
+	gen, err := protogen.Options{ParamFunc: flag.CommandLine.Set}.New(timeoutRequest("timeout=2s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = runProtoGen(gen); err != nil {
+		t.Fatal(err)
+	}
+	resp := gen.Response()
+	if resp.Error != nil {
+		t.Fatal(resp.GetError())
+	}
+	if len(resp.File) != 1 {
+		t.Fatalf("got %d files, want 1", len(resp.File))
+	}
+
+	got := []byte(resp.File[0].GetContent())
+	golden := filepath.Join("testdata", "timeout.gin.pb.go.golden")
+	if *update {
+		if err = os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("generated file differs from %s, run with -update to see the diff:\n%s", golden, got)
+	}
+}
+
+func TestNegativeTimeoutFlag(t *testing.T) {
+	defer func(timeout time.Duration) { args.Timeout = timeout }(args.Timeout)
+
+	gen, err := protogen.Options{ParamFunc: flag.CommandLine.Set}.New(timeoutRequest("timeout=-1s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = runProtoGen(gen); err == nil {
+		t.Fatal("expected error for a negative timeout")
+	}
+}
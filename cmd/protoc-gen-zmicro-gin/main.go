@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"time"
 
 	"google.golang.org/protobuf/compiler/protogen"
 )
@@ -18,6 +19,7 @@ var args = struct {
 	UseEncoding            bool
 	DisableErrorBadRequest bool
 	DisableClient          bool
+	Timeout                time.Duration
 }{
 	ShowVersion:            false,
 	Omitempty:              true,
@@ -27,6 +29,7 @@ var args = struct {
 	UseEncoding:            false,
 	DisableErrorBadRequest: false,
 	DisableClient:          true,
+	Timeout:                0,
 }
 
 func init() {
@@ -38,6 +41,7 @@ func init() {
 	flag.BoolVar(&args.UseEncoding, "use_encoding", false, "use the framework encoding")
 	flag.BoolVar(&args.DisableErrorBadRequest, "disable_error_bad_request", false, "disable error bad request")
 	flag.BoolVar(&args.DisableClient, "disable_client", true, "disable use client")
+	flag.DurationVar(&args.Timeout, "timeout", 0, "default timeout of the routes, e.g. 5s, a method overrides it with a zmicro:timeout=3s comment")
 }
 
 func main() {
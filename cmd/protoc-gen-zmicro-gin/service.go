@@ -2,6 +2,7 @@ package main
 
 import (
 	"strconv"
+	"time"
 
 	"google.golang.org/protobuf/compiler/protogen"
 )
@@ -36,6 +37,9 @@ type methodDesc struct {
 	HasBody      bool   // 是否有消息体
 	Body         string // 请求消息体
 	ResponseBody string // 回复消息体
+
+	// 超时, zmicro:timeout=3s 注释或 timeout 参数
+	Timeout time.Duration
 }
 
 func executeServiceDesc(g *protogen.GeneratedFile, s *serviceDesc) error {
@@ -72,7 +76,11 @@ func executeServiceDesc(g *protogen.GeneratedFile, s *serviceDesc) error {
 	g.P(`r := g.Group("")`)
 	g.P("{")
 	for _, m := range s.Methods {
-		g.P("r.", m.Method, `("`, m.Path, `", `, serverHandlerMethodName(s.ServiceType, m), "(srv))")
+		if m.Timeout > 0 {
+			g.P("r.", m.Method, `("`, m.Path, `", `, g.QualifiedGoIdent(transportHttpPackage.Ident("Timeout")), "(", durationExpr(g, m.Timeout), "), ", serverHandlerMethodName(s.ServiceType, m), "(srv))")
+		} else {
+			g.P("r.", m.Method, `("`, m.Path, `", `, serverHandlerMethodName(s.ServiceType, m), "(srv))")
+		}
 	}
 	g.P("}")
 	g.P("}")
@@ -177,6 +185,24 @@ func executeServiceDesc(g *protogen.GeneratedFile, s *serviceDesc) error {
 	return nil
 }
 
+// durationExpr returns d as an expression in the largest exact unit,
+// e.g. 3 * time.Second or 1500 * time.Microsecond.
+func durationExpr(g *protogen.GeneratedFile, d time.Duration) string {
+	for _, u := range []struct {
+		d    time.Duration
+		name string
+	}{
+		{time.Second, "Second"},
+		{time.Millisecond, "Millisecond"},
+		{time.Microsecond, "Microsecond"},
+	} {
+		if d%u.d == 0 {
+			return strconv.FormatInt(int64(d/u.d), 10) + " * " + g.QualifiedGoIdent(timePackage.Ident(u.name))
+		}
+	}
+	return strconv.FormatInt(int64(d), 10) + " * " + g.QualifiedGoIdent(timePackage.Ident("Nanosecond"))
+}
+
 func serverInterfaceName(serverType string) string {
 	return serverType + "HTTPServer"
 }
@@ -0,0 +1,39 @@
+package zmicro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Component is a unit whose lifecycle is managed by App, e.g. a message
+// consumer, a cron scheduler or an extra listener.
+//
+// Components are started in the order they were added, before the built-in
+// rpc and http servers, and are stopped in reverse order after the servers
+// have been drained.
+type Component interface {
+	// Start must not block; long-running work should be done in a goroutine.
+	Start(ctx context.Context) error
+	// Stop should return once the component has released its resources or
+	// ctx is done.
+	Stop(ctx context.Context) error
+}
+
+// Add appends components to the app. It must be called before Run.
+func (a *App) Add(c ...Component) *App {
+	a.opts.Components = append(a.opts.Components, c...)
+	return a
+}
+
+// stopComponents stops the first n components in reverse order.
+func (a *App) stopComponents(ctx context.Context, n int) error {
+	var errs []error
+	for i := n - 1; i >= 0; i-- {
+		c := a.opts.Components[i]
+		if err := c.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop component %T: %w", c, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,191 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zmicro-team/zmicro/core/config"
+	"github.com/zmicro-team/zmicro/core/log"
+	"github.com/zmicro-team/zmicro/core/util/env"
+)
+
+// CheckFunc reports whether a dependency, e.g. a database or etcd, is
+// usable. A non-nil error marks the service as not ready.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// run runs the check, a panic fails it instead of crashing the process.
+func (c check) run(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("admin: check %s panic: %v\n%s", c.name, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return c.fn(ctx)
+}
+
+// Admin serves health, readiness and runtime information of the service.
+type Admin struct {
+	opts    Options
+	ready   atomic.Bool
+	started time.Time
+
+	mu     sync.RWMutex
+	checks []check
+}
+
+func New(opts ...Option) *Admin {
+	return &Admin{
+		opts:    newOptions(opts...),
+		started: time.Now(),
+	}
+}
+
+// AddCheck registers a readiness check.
+func (a *Admin) AddCheck(name string, f CheckFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.checks = append(a.checks, check{name, f})
+}
+
+// SetReady sets whether the service accepts traffic. App sets it after
+// start and clears it as the first step of shutdown.
+func (a *Admin) SetReady(b bool) {
+	a.ready.Store(b)
+}
+
+// Ready runs all readiness checks and returns the failed ones.
+func (a *Admin) Ready(ctx context.Context) (bool, map[string]string) {
+	a.mu.RLock()
+	checks := a.checks
+	a.mu.RUnlock()
+
+	failed := make(map[string]string)
+	if !a.ready.Load() {
+		failed["app"] = "not serving"
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, a.opts.CheckTimeout)
+			defer cancel()
+			if err := c.run(ctx); err != nil {
+				mu.Lock()
+				failed[c.name] = err.Error()
+				mu.Unlock()
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return len(failed) == 0, failed
+}
+
+// Register mounts the admin endpoints on r.
+//
+//	GET /healthz       liveness
+//	GET /readyz        readiness, 503 while shutting down or a check fails
+//	GET /info          build and runtime info
+//	GET /config        effective config with secrets masked
+//	GET /loglevel      current log level
+//	PUT /loglevel      change log level, e.g. {"level":"debug"}
+//	ANY /debug/pprof/* pprof
+func (a *Admin) Register(r gin.IRouter) {
+	r.GET("/healthz", a.healthz)
+	r.GET("/readyz", a.readyz)
+	r.GET("/info", a.info)
+	r.GET("/config", a.config)
+	r.GET("/loglevel", a.logLevel)
+	r.PUT("/loglevel", a.logLevel)
+
+	if a.opts.Pprof {
+		g := r.Group("/debug/pprof")
+		g.GET("/", gin.WrapF(pprof.Index))
+		g.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		g.GET("/profile", gin.WrapF(pprof.Profile))
+		g.POST("/symbol", gin.WrapF(pprof.Symbol))
+		g.GET("/symbol", gin.WrapF(pprof.Symbol))
+		g.GET("/trace", gin.WrapF(pprof.Trace))
+		g.GET("/:name", func(c *gin.Context) {
+			pprof.Handler(c.Param("name")).ServeHTTP(c.Writer, c.Request)
+		})
+	}
+}
+
+func (a *Admin) healthz(c *gin.Context) {
+	c.String(http.StatusOK, "ok")
+}
+
+func (a *Admin) readyz(c *gin.Context) {
+	ok, failed := a.Ready(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "failed": failed})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (a *Admin) info(c *gin.Context) {
+	hostname, _ := os.Hostname()
+	info := gin.H{
+		"name":       a.opts.Name,
+		"env":        env.Get().String(),
+		"hostname":   hostname,
+		"pid":        os.Getpid(),
+		"started":    a.started,
+		"uptime":     time.Since(a.started).String(),
+		"go":         runtime.Version(),
+		"goroutines": runtime.NumGoroutine(),
+		"cpus":       runtime.NumCPU(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		settings := make(map[string]string, len(bi.Settings))
+		for _, s := range bi.Settings {
+			settings[s.Key] = s.Value
+		}
+		info["build"] = gin.H{
+			"path":     bi.Path,
+			"version":  bi.Main.Version,
+			"settings": settings,
+		}
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+func (a *Admin) config(c *gin.Context) {
+	cfg := a.opts.Config
+	if cfg == nil {
+		cfg = config.Default()
+	}
+	m, err := config.Dump(cfg)
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+func (a *Admin) logLevel(c *gin.Context) {
+	log.Default().LevelHandler().ServeHTTP(c.Writer, c.Request)
+}
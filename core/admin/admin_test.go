@@ -0,0 +1,105 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zmicro-team/zmicro/core/log"
+)
+
+func serve(r *gin.Engine, path string) int {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w.Code
+}
+
+func TestReadyz(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a := New()
+	r := gin.New()
+	a.Register(r)
+
+	if code := serve(r, "/healthz"); code != http.StatusOK {
+		t.Fatalf("healthz: got %d", code)
+	}
+	if code := serve(r, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("readyz before start: got %d", code)
+	}
+
+	a.SetReady(true)
+	if code := serve(r, "/readyz"); code != http.StatusOK {
+		t.Fatalf("readyz after start: got %d", code)
+	}
+
+	var healthy bool
+	a.AddCheck("db", func(context.Context) error {
+		if !healthy {
+			return errors.New("ping failed")
+		}
+		return nil
+	})
+	if code := serve(r, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("readyz with failed check: got %d", code)
+	}
+	healthy = true
+	if code := serve(r, "/readyz"); code != http.StatusOK {
+		t.Fatalf("readyz with passed check: got %d", code)
+	}
+
+	a.SetReady(false)
+	if code := serve(r, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("readyz during shutdown: got %d", code)
+	}
+}
+
+func TestReadyCheckPanic(t *testing.T) {
+	a := New()
+	a.SetReady(true)
+	a.AddCheck("cache", func(context.Context) error {
+		var m map[string]int
+		m["boom"]++
+		return nil
+	})
+	ok, failed := a.Ready(context.Background())
+	if ok || !strings.HasPrefix(failed["cache"], "panic: ") {
+		t.Fatalf("got %v %v, want the panic as a failed check", ok, failed)
+	}
+}
+
+func TestPprof(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	New().Register(r)
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline", "/debug/pprof/heap"} {
+		if code := serve(r, path); code != http.StatusOK {
+			t.Fatalf("%s: got %d", path, code)
+		}
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	New().Register(r)
+
+	old := log.Default().Level()
+	defer log.Default().SetLevel(old)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(`{"level":"debug"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body.String())
+	}
+	if lv := log.Default().Level(); lv != log.DebugLevel {
+		t.Fatalf("got level %s, want debug", lv)
+	}
+}
@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/zmicro-team/zmicro/core/config"
+)
+
+type Options struct {
+	// Name is the application name reported by the info endpoint.
+	Name string
+	// Config is dumped, with secrets masked, by the config endpoint.
+	// nil means config.Default().
+	Config config.IConfig
+	// CheckTimeout bounds every readiness check. default 3s
+	CheckTimeout time.Duration
+	// Pprof enables the /debug/pprof endpoints. default true
+	Pprof bool
+}
+
+type Option func(*Options)
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		CheckTimeout: 3 * time.Second,
+		Pprof:        true,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
+
+func Name(s string) Option {
+	return func(o *Options) {
+		o.Name = s
+	}
+}
+
+func Config(c config.IConfig) Option {
+	return func(o *Options) {
+		o.Config = c
+	}
+}
+
+func CheckTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.CheckTimeout = d
+	}
+}
+
+func Pprof(b bool) Option {
+	return func(o *Options) {
+		o.Pprof = b
+	}
+}
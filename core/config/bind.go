@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/zmicro-team/zmicro/core/log"
+)
+
+var (
+	validateOnce sync.Once
+	validate     *validator.Validate
+)
+
+// Validator returns the validator used by Bind, e.g. to register custom
+// validations. Field names in errors are config keys.
+func Validator() *validator.Validate {
+	validateOnce.Do(func() {
+		validate = validator.New()
+		validate.RegisterTagNameFunc(func(f reflect.StructField) string {
+			if name, _, _ := strings.Cut(f.Tag.Get("mapstructure"), ","); name != "" {
+				return name
+			}
+			return strings.ToLower(f.Name)
+		})
+	})
+	return validate
+}
+
+// Bind decodes key of the default config into a T, key "" being the whole
+// config. Fields that are not set take the value of their `default` tag,
+// e.g. `default:"10s"` or `default:"a,b"` for a slice, then the result is
+// checked against the `validate` tags, see github.com/go-playground/validator.
+// The error lists all the invalid keys.
+//
+// NOTE: defaults are not applied inside nil pointers, maps and slices.
+func Bind[T any](key string) (T, error) {
+	return bind[T](Default(), key)
+}
+
+// Watch calls f when key of the default config changes, with the values
+// bound by Bind before and after the change. The changes that do not bind
+// are logged and skipped.
+func Watch[T any](key string, f func(old, new T)) error {
+	c := Default()
+	cur, err := bind[T](c, key)
+	if err != nil {
+		return err
+	}
+
+	w, ok := c.(Watcher)
+	if !ok {
+		// the config never changes
+		return nil
+	}
+
+	var mu sync.Mutex
+	w.AddCallback(func(c IConfig) {
+		v, err := bind[T](c, key)
+		if err != nil {
+			log.Errorf("config: watch %q: %v", key, err)
+			return
+		}
+		mu.Lock()
+		old := cur
+		if reflect.DeepEqual(old, v) {
+			mu.Unlock()
+			return
+		}
+		cur = v
+		mu.Unlock()
+		f(old, v)
+	})
+	return nil
+}
+
+func bind[T any](c IConfig, key string) (T, error) {
+	var val T
+
+	rv := reflect.ValueOf(&val).Elem()
+	if err := setDefaults(rv); err != nil {
+		return val, err
+	}
+
+	var err error
+	if key == "" {
+		err = c.Unmarshal(&val)
+	} else {
+		err = c.Scan(key, &val)
+	}
+	if err != nil {
+		return val, fmt.Errorf("config: bind %q: %w", key, err)
+	}
+
+	if reflect.Indirect(rv).Kind() != reflect.Struct {
+		return val, nil
+	}
+	if err = Validator().Struct(val); err != nil {
+		return val, validationError(key, err)
+	}
+	return val, nil
+}
+
+// setDefaults sets the zero fields of v to their `default` tag.
+func setDefaults(v reflect.Value) error {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if def, ok := f.Tag.Lookup("default"); ok && fv.IsZero() {
+			if err := decodeDefault(def, fv); err != nil {
+				return fmt.Errorf("config: default of %s.%s: %w", t.Name(), f.Name, err)
+			}
+			continue
+		}
+		if err := setDefaults(fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeDefault(s string, v reflect.Value) error {
+	d, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		WeaklyTypedInput: true,
+		Result:           v.Addr().Interface(),
+	})
+	if err != nil {
+		return err
+	}
+	return d.Decode(s)
+}
+
+func validationError(key string, err error) error {
+	errs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return fmt.Errorf("config: validate %q: %w", key, err)
+	}
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		// strip the type name
+		_, p, _ := strings.Cut(e.Namespace(), ".")
+		if key != "" {
+			p = key + "." + p
+		}
+		msg := p + ": " + e.Tag()
+		if e.Param() != "" {
+			msg += "=" + e.Param()
+		}
+		msgs = append(msgs, msg)
+	}
+	return fmt.Errorf("config: invalid %s", strings.Join(msgs, ", "))
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindServer struct {
+	Addr    string        `validate:"required"`
+	Timeout time.Duration `default:"5s"`
+	Methods []string      `default:"GET,POST"`
+	Limit   int           `default:"10" validate:"min=1"`
+}
+
+func TestBind(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+server:
+  addr: ":8080"
+  limit: 20
+bad:
+  limit: 0
+`
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := Default()
+	defer ResetDefault(old)
+	ResetDefault(New(Path(p), AutoReload(false)))
+
+	s, err := Bind[bindServer]("server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Addr != ":8080" || s.Timeout != 5*time.Second || s.Limit != 20 ||
+		strings.Join(s.Methods, ",") != "GET,POST" {
+		t.Fatalf("unexpected server: %+v", s)
+	}
+
+	_, err = Bind[bindServer]("bad")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"bad.addr: required", "bad.limit: min=1"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(p, []byte("a:\n  addr: x\nb:\n  addr: y\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := Default()
+	defer ResetDefault(old)
+	c := New(Path(p), AutoReload(false)).(*Config)
+	ResetDefault(c)
+
+	var calls []string
+	err := Watch("a", func(old, new bindServer) {
+		calls = append(calls, old.Addr+"->"+new.Addr)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{
+		"a:\n  addr: x\nb:\n  addr: z\n",
+		"a:\n  addr: w\nb:\n  addr: z\n",
+	} {
+		if err = os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		c.reload()
+	}
+	if len(calls) != 1 || calls[0] != "x->w" {
+		t.Fatalf("unexpected calls: %v", calls)
+	}
+}
+
+func TestWatchStatic(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(p, []byte("a:\n  addr: x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := Default()
+	defer ResetDefault(old)
+	// a Snapshot is an IConfig that does not change
+	var c IConfig = New(Path(p), AutoReload(false)).(*Config).Snapshot()
+	if _, ok := c.(Watcher); ok {
+		t.Fatal("a Snapshot is not a Watcher")
+	}
+	ResetDefault(c)
+
+	if err := Watch("a", func(old, new bindServer) {}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := Bind[bindServer]("a"); err != nil || v.Addr != "x" {
+		t.Fatalf("got %+v %v", v, err)
+	}
+}
@@ -3,135 +3,343 @@ package config
 import (
 	"bytes"
 	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
+
+	"github.com/zmicro-team/zmicro/core/log"
+	"github.com/zmicro-team/zmicro/core/util/fswatch"
 )
 
-var defaultConfig = New()
+// holder holds an IConfig in an atomic.Pointer, whatever its type.
+type holder struct {
+	IConfig
+}
+
+var defaultConfig atomic.Pointer[holder]
+
+func init() {
+	ResetDefault(New())
+}
 
 func Default() IConfig {
-	return defaultConfig
+	return defaultConfig.Load().IConfig
 }
 
+// ResetDefault replaces the default config, it is safe to call while
+// other goroutines use the default config.
 func ResetDefault(c IConfig) {
-	defaultConfig = c
-
-	Unmarshal = defaultConfig.Unmarshal
-	Scan = defaultConfig.Scan
-	Get = defaultConfig.Get
-	GetString = defaultConfig.GetString
-	GetBool = defaultConfig.GetBool
-	GetInt = defaultConfig.GetInt
-	GetFloat64 = defaultConfig.GetFloat64
-	GetDuration = defaultConfig.GetDuration
-	GetIntSlice = defaultConfig.GetIntSlice
-	GetStringSlice = defaultConfig.GetStringSlice
-	GetStringMap = defaultConfig.GetStringMap
+	defaultConfig.Store(&holder{c})
 }
 
+// Config merges its layers, from lowest to highest precedence:
+//  1. the base file given by Path
+//  2. the env file, e.g. config.develop.yaml next to config.yaml, see EnvFile
+//  3. the files given by Paths
+//  4. the Sources, e.g. a remote KV store
+//  5. environment variables, see EnvPrefix
+//
+// String values that are secret references, e.g. ${env:DB_PASS}, are
+// replaced by the secrets, see SecretResolvers.
+//
+// When a file or a source changes, all layers are merged again into a new
+// Snapshot. If it differs, the ChangeFuncs may reject it, otherwise it
+// atomically replaces the current one as the next version and the
+// callbacks are called. The getters read the current snapshot, so they are
+// safe to call during a reload; use Snapshot for consistent reads across
+// several keys.
 type Config struct {
-	v    *viper.Viper
-	data []byte
 	opts Options
+	snap atomic.Pointer[Snapshot]
+
+	mu        sync.RWMutex
+	callbacks []func(IConfig)
+	onChange  []ChangeFunc
+
+	reloadMu sync.Mutex
+	watcher  *fswatch.Watcher
 }
 
+// New is like Load but panics if the config cannot be loaded.
 func New(opts ...Option) IConfig {
+	c, err := Load(opts...)
+	if err != nil {
+		panic("load config error: " + err.Error())
+	}
+	return c
+}
+
+// Load creates a config from the given options and returns an error
+// if the config cannot be read.
+func Load(opts ...Option) (IConfig, error) {
 	options := Options{
-		Type: "yaml",
+		Type:            "yaml",
+		AutoReload:      true,
+		SecretResolvers: []SecretResolver{EnvSecret(), FileSecret()},
 	}
 
 	for _, o := range opts {
 		o(&options)
 	}
 
-	c := Config{
-		v:    viper.New(),
-		opts: options,
+	c := &Config{
+		opts:      options,
+		callbacks: options.Callbacks,
+		onChange:  options.OnChange,
 	}
 
 	if err := c.load(); err != nil {
-		panic("load config error")
+		// the sources may hold connections or watches
+		_ = c.Close()
+		return nil, err
 	}
-	return &c
+	return c, nil
 }
 
 func (c *Config) load() error {
-	if c.opts.Type != "" {
-		c.v.SetConfigType(c.opts.Type)
+	snap, files, err := c.build()
+	if err != nil {
+		return err
 	}
+	snap.version = 1
+	c.snap.Store(snap)
 
-	if c.opts.Path == "" {
+	if c.opts.Path == "" && len(c.opts.Sources) == 0 {
 		return nil
 	}
 
-	c.v.SetConfigFile(c.opts.Path)
+	if c.opts.AutoReload {
+		if len(files) > 0 {
+			c.watcher, err = fswatch.Watch(files, func(string) { c.reload() })
+			if err != nil {
+				return err
+			}
+		}
+		for _, s := range c.opts.Sources {
+			if err = s.Watch(c.reload); err != nil {
+				return err
+			}
+		}
+	}
+	c.callback()
+	return nil
+}
 
-	if err := c.v.ReadInConfig(); err != nil {
-		return err
+// build merges all layers into a new snapshot, and returns it with the
+// files to watch.
+func (c *Config) build() (*Snapshot, []string, error) {
+	v := viper.New()
+	if c.opts.Type != "" {
+		v.SetConfigType(c.opts.Type)
 	}
+	if c.opts.EnvPrefix != "" {
+		v.SetEnvPrefix(c.opts.EnvPrefix)
+		v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+		v.AutomaticEnv()
+	}
+
+	var files []string
+	if c.opts.Path != "" {
+		v.SetConfigFile(c.opts.Path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, nil, err
+		}
+		files = append(files, c.opts.Path)
 
-	callback := func(cfg IConfig) {
-		for i := range c.opts.Callbacks {
-			c.opts.Callbacks[i](cfg)
+		if c.opts.EnvFile {
+			p := envFile(c.opts.Path, v.GetString("app.mode"))
+			// the env file is optional, but watched so it can be added later
+			files = append(files, p)
+			if exists(p) {
+				v.SetConfigFile(p)
+				if err := v.MergeInConfig(); err != nil {
+					return nil, nil, err
+				}
+			}
 		}
 	}
-	c.v.OnConfigChange(func(e fsnotify.Event) {
-		data, _ := json.Marshal(c.v.AllSettings())
-		if !bytes.Equal(data, c.data) {
-			c.data = data
-			callback(c)
+	for _, p := range c.opts.Paths {
+		v.SetConfigFile(p)
+		if err := v.MergeInConfig(); err != nil {
+			return nil, nil, err
 		}
-	})
-	c.v.WatchConfig()
-	c.data, _ = json.Marshal(c.v.AllSettings())
-	callback(c)
-	return nil
+		files = append(files, p)
+	}
+	for _, s := range c.opts.Sources {
+		m, err := s.Load()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = v.MergeConfigMap(m); err != nil {
+			return nil, nil, err
+		}
+	}
+	secrets, err := resolveSecrets(v, c.opts.SecretResolvers)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, _ := json.Marshal(v.AllSettings())
+	return &Snapshot{v: v, data: data, secrets: secrets, envPrefix: c.opts.EnvPrefix}, files, nil
+}
+
+// reload merges all layers again and applies the result if it differs from
+// the current one and no ChangeFunc rejects it.
+func (c *Config) reload() {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	prev := c.Snapshot()
+	next, _, err := c.build()
+	if err != nil {
+		log.Errorf("config: reload failed, keep version %d: %v", prev.version, err)
+		return
+	}
+	if bytes.Equal(next.data, prev.data) {
+		return
+	}
+	next.version = prev.version + 1
+
+	c.mu.RLock()
+	onChange := c.onChange
+	c.mu.RUnlock()
+
+	d := diff(prev.v.AllSettings(), next.v.AllSettings())
+	d.Version = next.version
+	for i, f := range onChange {
+		if err = f(c.view(next), d); err != nil {
+			// take back the accepted ones
+			for j := i - 1; j >= 0; j-- {
+				_ = onChange[j](c.view(prev), d.reverse(prev.version))
+			}
+			log.Errorf("config: version %d rejected, keep version %d: %v", next.version, prev.version, err)
+			return
+		}
+	}
+
+	c.snap.Store(next)
+	log.Infof("config: version %d applied, changed: %v, added: %v, removed: %v",
+		d.Version, d.Changed, d.Added, d.Removed)
+
+	c.callback()
+}
+
+// view returns a Config that reads snap only.
+func (c *Config) view(snap *Snapshot) *Config {
+	v := &Config{opts: c.opts}
+	v.snap.Store(snap)
+	return v
+}
+
+func (c *Config) callback() {
+	c.mu.RLock()
+	callbacks := c.callbacks
+	c.mu.RUnlock()
+	for i := range callbacks {
+		callbacks[i](c)
+	}
+}
+
+// Snapshot returns the current snapshot.
+func (c *Config) Snapshot() *Snapshot {
+	return c.snap.Load()
+}
+
+// Version returns the version of the config, starting at 1 and increased
+// by each applied reload.
+func (c *Config) Version() uint64 {
+	return c.Snapshot().version
+}
+
+func (c *Config) isSecret(value string) bool {
+	return c.Snapshot().isSecret(value)
+}
+
+// String returns the settings as JSON, masked like Dump.
+func (c *Config) String() string {
+	return c.Snapshot().String()
+}
+
+// Close stops watching the files and the sources.
+func (c *Config) Close() error {
+	var err error
+	if c.watcher != nil {
+		err = c.watcher.Close()
+	}
+	for _, s := range c.opts.Sources {
+		if e := s.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// AddCallback registers callbacks that are called when the config changes.
+// Unlike Callbacks, they are not called on the initial load.
+func (c *Config) AddCallback(f ...func(IConfig)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	callbacks := make([]func(IConfig), 0, len(c.callbacks)+len(f))
+	callbacks = append(callbacks, c.callbacks...)
+	c.callbacks = append(callbacks, f...)
+}
+
+// OnChange registers funcs that are called with the new config and the
+// diff before a reload is applied, and can reject it. When one rejects it,
+// the ones called before it are called again with the current config and
+// the reverse diff.
+func (c *Config) OnChange(f ...ChangeFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	onChange := make([]ChangeFunc, 0, len(c.onChange)+len(f))
+	onChange = append(onChange, c.onChange...)
+	c.onChange = append(onChange, f...)
 }
 
 func (c *Config) Unmarshal(val any) error {
-	return c.v.Unmarshal(val)
+	return c.Snapshot().Unmarshal(val)
 }
 
 func (c *Config) Scan(key string, val any) error {
-	return c.v.UnmarshalKey(key, val)
+	return c.Snapshot().Scan(key, val)
 }
 
 func (c *Config) Get(key string) any {
-	return c.v.Get(key)
+	return c.Snapshot().Get(key)
 }
 
 func (c *Config) GetString(key string) string {
-	return c.v.GetString(key)
+	return c.Snapshot().GetString(key)
 }
 
 func (c *Config) GetBool(key string) bool {
-	return c.v.GetBool(key)
+	return c.Snapshot().GetBool(key)
 }
 
 func (c *Config) GetInt(key string) int {
-	return c.v.GetInt(key)
+	return c.Snapshot().GetInt(key)
 }
 
 func (c *Config) GetFloat64(key string) float64 {
-	return c.v.GetFloat64(key)
+	return c.Snapshot().GetFloat64(key)
 }
 
 func (c *Config) GetDuration(key string) time.Duration {
-	return c.v.GetDuration(key)
+	return c.Snapshot().GetDuration(key)
 }
 
 func (c *Config) GetIntSlice(key string) []int {
-	return c.v.GetIntSlice(key)
+	return c.Snapshot().GetIntSlice(key)
 }
 
 func (c *Config) GetStringSlice(key string) []string {
-	return c.v.GetStringSlice(key)
+	return c.Snapshot().GetStringSlice(key)
 }
 
 func (c *Config) GetStringMap(key string) map[string]any {
-	return c.v.GetStringMap(key)
+	return c.Snapshot().GetStringMap(key)
 }
 
 type IConfig interface {
@@ -148,16 +356,55 @@ type IConfig interface {
 	GetStringMap(key string) map[string]any
 }
 
-var (
-	Unmarshal      = defaultConfig.Unmarshal
-	Scan           = defaultConfig.Scan
-	Get            = defaultConfig.Get
-	GetString      = defaultConfig.GetString
-	GetBool        = defaultConfig.GetBool
-	GetInt         = defaultConfig.GetInt
-	GetFloat64     = defaultConfig.GetFloat64
-	GetDuration    = defaultConfig.GetDuration
-	GetIntSlice    = defaultConfig.GetIntSlice
-	GetStringSlice = defaultConfig.GetStringSlice
-	GetStringMap   = defaultConfig.GetStringMap
-)
+// Watcher is implemented by the configs that change, e.g. *Config. Check
+// for it with a type assertion on an IConfig.
+type Watcher interface {
+	AddCallback(f ...func(IConfig))
+	OnChange(f ...ChangeFunc)
+}
+
+// The functions below use the default config.
+
+func Unmarshal(val any) error {
+	return Default().Unmarshal(val)
+}
+
+func Scan(key string, val any) error {
+	return Default().Scan(key, val)
+}
+
+func Get(key string) any {
+	return Default().Get(key)
+}
+
+func GetString(key string) string {
+	return Default().GetString(key)
+}
+
+func GetBool(key string) bool {
+	return Default().GetBool(key)
+}
+
+func GetInt(key string) int {
+	return Default().GetInt(key)
+}
+
+func GetFloat64(key string) float64 {
+	return Default().GetFloat64(key)
+}
+
+func GetDuration(key string) time.Duration {
+	return Default().GetDuration(key)
+}
+
+func GetIntSlice(key string) []int {
+	return Default().GetIntSlice(key)
+}
+
+func GetStringSlice(key string) []string {
+	return Default().GetStringSlice(key)
+}
+
+func GetStringMap(key string) map[string]any {
+	return Default().GetStringMap(key)
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type mapSource map[string]any
+
+func (s mapSource) Load() (map[string]any, error) { return s, nil }
+func (mapSource) Watch(func()) error              { return nil }
+func (mapSource) Close() error                    { return nil }
+
+// failSource fails to load and records whether it was closed.
+type failSource struct{ closed bool }
+
+func (*failSource) Load() (map[string]any, error) { return nil, errors.New("load failed") }
+func (*failSource) Watch(func()) error            { return nil }
+func (s *failSource) Close() error                { s.closed = true; return nil }
+
+func TestLoadCloseSources(t *testing.T) {
+	s := &failSource{}
+	if _, err := Load(Sources(s)); err == nil {
+		t.Fatal("expected load error")
+	}
+	if !s.closed {
+		t.Fatal("source not closed")
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	base := write("config.yaml", `
+app:
+  mode: test
+  name: base
+http:
+  addr: ":8080"
+rpc:
+  addr: ":9090"
+db:
+  dsn: base
+`)
+	write("config.test.yaml", `
+app:
+  name: env
+`)
+	extra := write("extra.yaml", `
+http:
+  addr: ":8081"
+`)
+	t.Setenv("ZMTEST_DB_DSN", "env")
+
+	c, err := Load(
+		Path(base),
+		EnvFile(true),
+		Paths(extra),
+		Sources(mapSource{"rpc": map[string]any{"addr": ":9091"}}),
+		EnvPrefix("ZMTEST"),
+		AutoReload(false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.(*Config).Close()
+
+	for key, want := range map[string]string{
+		"app.name":  "env",
+		"http.addr": ":8081",
+		"rpc.addr":  ":9091",
+		"db.dsn":    "env",
+	} {
+		if got := c.GetString(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestEnvPrefixStruct(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(p, []byte("app:\n  name: base\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ZMTEST_APP_NAME", "env")
+	t.Setenv("ZMTEST_TRACER_ADDR", "jaeger:4317")
+	t.Setenv("ZMTEST_HTTP_READTIMEOUT", "3s")
+	t.Setenv("ZMTEST_REGISTRY_ETCDADDR", "a,b")
+
+	c, err := Load(Path(p), EnvPrefix("ZMTEST"), AutoReload(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.(*Config).Close()
+
+	type tracer struct {
+		Addr string
+	}
+	var v struct {
+		App struct {
+			Name string
+		}
+		Tracer tracer
+		Http   struct {
+			ReadTimeout time.Duration
+		}
+		Registry struct {
+			EtcdAddr []string
+		}
+	}
+	if err = c.Unmarshal(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.App.Name != "env" || v.Tracer.Addr != "jaeger:4317" || v.Http.ReadTimeout != 3*time.Second ||
+		len(v.Registry.EtcdAddr) != 2 {
+		t.Fatalf("env not applied: %+v", v)
+	}
+
+	var tr tracer
+	if err = c.Scan("tracer", &tr); err != nil {
+		t.Fatal(err)
+	}
+	if tr.Addr != "jaeger:4317" {
+		t.Fatalf("Scan: got %+v", tr)
+	}
+}
+
+func TestConcurrentReload(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	content := func(i int) []byte {
+		return []byte("a:\n  b: " + strconv.Itoa(i) + "\n  password: p@ss\n  list:\n    - token: t0k3n\n")
+	}
+	if err := os.WriteFile(p, content(0), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := New(Path(p), AutoReload(false)).(*Config)
+	old := Default()
+	defer ResetDefault(old)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i < 20; i++ {
+			if err := os.WriteFile(p, content(i), 0o644); err != nil {
+				t.Error(err)
+				return
+			}
+			c.reload()
+			ResetDefault(c)
+		}
+	}()
+	// Dump runs concurrently with the readers, e.g. the admin endpoint
+	dumped := make(chan struct{})
+	go func() {
+		defer close(dumped)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				if _, err := Dump(c); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			<-dumped
+			if got := c.GetInt("a.b"); got != 19 {
+				t.Fatalf("a.b = %d, want 19", got)
+			}
+			if got := c.GetString("a.password"); got != "p@ss" {
+				t.Fatalf("a.password = %q, masked by Dump", got)
+			}
+			return
+		default:
+			var m map[string]any
+			_ = Scan("a", &m)
+			_ = GetString("a.b")
+			_ = c.Snapshot().GetInt("a.b")
+			_ = c.Get("a.list")
+		}
+	}
+}
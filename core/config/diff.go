@@ -0,0 +1,91 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Diff is the change of a config reload. The keys are the full keys of the
+// leaves, e.g. http.addr, sorted.
+type Diff struct {
+	// Version is the version of the new config.
+	Version uint64
+	Changed []string
+	Added   []string
+	Removed []string
+}
+
+// ChangeFunc is called with the new config and its diff before a reload is
+// applied. If it returns an error the reload is aborted and the previous
+// config is kept.
+type ChangeFunc func(c IConfig, d Diff) error
+
+// Empty reports whether nothing changed.
+func (d Diff) Empty() bool {
+	return len(d.Changed) == 0 && len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// Has reports whether key or a key under it changed.
+func (d Diff) Has(key string) bool {
+	key = strings.ToLower(key)
+	for _, keys := range [][]string{d.Changed, d.Added, d.Removed} {
+		for _, k := range keys {
+			if k == key || strings.HasPrefix(k, key+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reverse returns the diff from the new config back to the old one.
+func (d Diff) reverse(version uint64) Diff {
+	return Diff{
+		Version: version,
+		Changed: d.Changed,
+		Added:   d.Removed,
+		Removed: d.Added,
+	}
+}
+
+func diff(old, new map[string]any) Diff {
+	o, n := flatten("", old, nil), flatten("", new, nil)
+	var d Diff
+	for k, v := range n {
+		ov, ok := o[k]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, k)
+		case !reflect.DeepEqual(ov, v):
+			d.Changed = append(d.Changed, k)
+		}
+	}
+	for k := range o {
+		if _, ok := n[k]; !ok {
+			d.Removed = append(d.Removed, k)
+		}
+	}
+	sort.Strings(d.Changed)
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	return d
+}
+
+// flatten maps the full keys of the leaves of m to their values.
+func flatten(prefix string, m map[string]any, out map[string]any) map[string]any {
+	if out == nil {
+		out = make(map[string]any)
+	}
+	for k, v := range m {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		if mm, ok := v.(map[string]any); ok && len(mm) > 0 {
+			flatten(k, mm, out)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReloadDiffAndReject(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("http:\n  addr: \":8080\"\nrpc:\n  addr: \":9090\"\n")
+	c := New(Path(p), AutoReload(false)).(*Config)
+
+	var (
+		diffs  []Diff
+		called int
+	)
+	c.OnChange(
+		func(_ IConfig, d Diff) error {
+			called++
+			return nil
+		},
+		func(next IConfig, d Diff) error {
+			if next.GetString("http.addr") == "" {
+				return errors.New("http.addr is required")
+			}
+			diffs = append(diffs, d)
+			return nil
+		},
+	)
+
+	write("http:\n  addr: \":8081\"\ndb:\n  dsn: x\n")
+	c.reload()
+	want := Diff{Version: 2, Changed: []string{"http.addr"}, Added: []string{"db.dsn"}, Removed: []string{"rpc.addr"}}
+	if len(diffs) != 1 || !reflect.DeepEqual(diffs[0], want) {
+		t.Fatalf("diffs = %+v, want %+v", diffs, want)
+	}
+	if !diffs[0].Has("http") || diffs[0].Has("app") {
+		t.Fatal("unexpected Has")
+	}
+
+	// rejected: the first func is called again to go back
+	write("db:\n  dsn: y\n")
+	c.reload()
+	if c.Version() != 2 || c.GetString("http.addr") != ":8081" || c.GetString("db.dsn") != "x" {
+		t.Fatalf("rejected reload applied: version %d", c.Version())
+	}
+	if called != 3 {
+		t.Fatalf("called = %d, want 3", called)
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"strings"
+)
+
+const maskedValue = "******"
+
+// secretKeys are the key fragments whose values are masked by Dump.
+var secretKeys = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"credential",
+	"privatekey",
+	"private_key",
+	"accesskey",
+	"access_key",
+	"apikey",
+	"api_key",
+}
+
+// Dump returns all settings of c, with the values of secret-looking keys
+// and the resolved secret references masked, so it is safe to print or
+// expose.
+func Dump(c IConfig) (map[string]any, error) {
+	m := make(map[string]any)
+	if err := c.Unmarshal(&m); err != nil {
+		return nil, err
+	}
+	d := dumper{}
+	if s, ok := c.(interface{ isSecret(string) bool }); ok {
+		d.isSecret = s.isSecret
+	}
+	return d.maskMap(m), nil
+}
+
+type dumper struct {
+	isSecret func(value string) bool
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range secretKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskMap returns a masked copy of m. m is not modified, its maps and
+// slices are shared with the snapshot.
+func (d dumper) maskMap(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		if isSecretKey(k) {
+			out[k] = maskedValue
+			continue
+		}
+		out[k] = d.maskValue(v)
+	}
+	return out
+}
+
+func (d dumper) maskValue(v any) any {
+	switch vv := v.(type) {
+	case map[string]any:
+		return d.maskMap(vv)
+	case []any:
+		out := make([]any, len(vv))
+		for i := range vv {
+			out[i] = d.maskValue(vv[i])
+		}
+		return out
+	case string:
+		if d.isSecret != nil && d.isSecret(vv) {
+			return maskedValue
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+app:
+  name: test
+db:
+  user: root
+  password: p@ss
+oauth:
+  clients:
+    - id: web
+      clientSecret: s3cr3t
+`
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(Path(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Dump(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := m["db"].(map[string]any)
+	if db["user"] != "root" || db["password"] != maskedValue {
+		t.Fatalf("unexpected db: %v", db)
+	}
+	client := m["oauth"].(map[string]any)["clients"].([]any)[0].(map[string]any)
+	if client["id"] != "web" || client["clientsecret"] != maskedValue {
+		t.Fatalf("unexpected client: %v", client)
+	}
+}
+
+func TestDumpKeepsConfig(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+db:
+  replicas:
+    - host: a
+      password: p@ss
+  hosts:
+    - ${env:DUMP_TEST_PASS}
+    - plain
+`
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DUMP_TEST_PASS", "s3cret")
+	c, err := Load(Path(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func() string {
+		m := make(map[string]any)
+		if err := c.Unmarshal(&m); err != nil {
+			t.Fatal(err)
+		}
+		b, _ := json.Marshal(m)
+		return string(b)
+	}
+	before := read()
+	if _, err = Dump(c); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.(*Config).Snapshot().String()
+	if after := read(); after != before {
+		t.Fatalf("Dump changed the config: %s, was %s", after, before)
+	}
+}
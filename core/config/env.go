@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// unmarshalEnv decodes into val the environment variables of its fields
+// under key, e.g. ZMICRO_TRACER_ADDR for tracer.addr with prefix ZMICRO.
+// viper only overrides the keys found in another layer on Unmarshal, this
+// adds the keys that are only in the struct.
+func unmarshalEnv(prefix, key string, val any) error {
+	if prefix == "" {
+		return nil
+	}
+	t := reflect.TypeOf(val)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	m := make(map[string]any)
+	envFields(strings.ToUpper(prefix)+"_", key, t, m)
+	if len(m) == 0 {
+		return nil
+	}
+	d, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		WeaklyTypedInput: true,
+		Result:           val,
+	})
+	if err != nil {
+		return err
+	}
+	return d.Decode(m)
+}
+
+// envFields sets in m the fields of t that have an environment variable.
+func envFields(prefix, key string, t reflect.Type, m map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if strings.Contains(opts, "squash") && ft.Kind() == reflect.Struct {
+			envFields(prefix, key, ft, m)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		path := strings.ToLower(name)
+		if key != "" {
+			path = key + "." + path
+		}
+
+		switch {
+		case ft.Kind() == reflect.Struct && ft != reflect.TypeOf(time.Time{}):
+			sub := make(map[string]any)
+			envFields(prefix, path, ft, sub)
+			if len(sub) > 0 {
+				m[name] = sub
+			}
+		case ft.Kind() == reflect.Map:
+			// the keys of maps are not known
+		default:
+			env := prefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+			if v, ok := os.LookupEnv(env); ok {
+				m[name] = v
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package etcd
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/zmicro-team/zmicro/core/config"
+	"github.com/zmicro-team/zmicro/core/log"
+)
+
+var _ config.Source = (*Source)(nil)
+
+// Source is a config.Source backed by the keys under a prefix in etcd.
+//
+// The key path relative to the prefix is the config key, and the value is
+// parsed as yaml, e.g. with prefix /zmicro/config/example:
+//
+//	/zmicro/config/example/http/addr  ":5180"  =>  http.addr: ":5180"
+//
+// The value of the prefix itself, if any, is merged as a yaml document.
+type Source struct {
+	opts   Options
+	client *clientv3.Client
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func New(opts ...Option) (*Source, error) {
+	options := newOptions(opts...)
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   options.Endpoints,
+		DialTimeout: options.DialTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Source{opts: options, client: client}, nil
+}
+
+func (s *Source) prefix() string {
+	return strings.TrimSuffix(s.opts.Prefix, "/")
+}
+
+func (s *Source) Load() (map[string]any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.DialTimeout)
+	defer cancel()
+
+	resp, err := s.client.Get(ctx, s.prefix(), clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(s.prefix(), resp.Kvs)
+}
+
+// decode merges the keys under prefix into settings.
+func decode(prefix string, kvs []*mvccpb.KeyValue) (map[string]any, error) {
+	settings := make(map[string]any)
+	for _, kv := range kvs {
+		key := strings.TrimPrefix(string(kv.Key), prefix)
+		if key != "" && !strings.HasPrefix(key, "/") {
+			// a sibling sharing the prefix, e.g. /example2 for /example
+			continue
+		}
+		if err := merge(settings, strings.Trim(key, "/"), kv.Value); err != nil {
+			return nil, err
+		}
+	}
+	return settings, nil
+}
+
+// merge sets the yaml value at the slash separated path of settings.
+func merge(settings map[string]any, path string, value []byte) error {
+	var v any
+	if err := yaml.Unmarshal(value, &v); err != nil {
+		return err
+	}
+
+	if path == "" {
+		// keys are sorted, so the prefix itself comes first and the
+		// keys below it take precedence
+		if m, ok := v.(map[string]any); ok {
+			for k, vv := range m {
+				settings[k] = vv
+			}
+		}
+		return nil
+	}
+
+	parts := strings.Split(path, "/")
+	m := settings
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := m[p].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			m[p] = next
+		}
+		m = next
+	}
+	m[parts[len(parts)-1]] = v
+	return nil
+}
+
+func (s *Source) Watch(onChange func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		for {
+			wc := s.client.Watch(ctx, s.prefix(), clientv3.WithPrefix())
+			for resp := range wc {
+				if err := resp.Err(); err != nil {
+					log.Errorf("config: etcd watch %s: %v", s.prefix(), err)
+					continue
+				}
+				if len(resp.Events) > 0 {
+					onChange()
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			// the watch channel was closed by etcd, e.g. compaction
+			time.Sleep(time.Second)
+		}
+	}()
+	return nil
+}
+
+func (s *Source) Close() error {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+		s.cancel = nil
+	}
+	s.mu.Unlock()
+	return s.client.Close()
+}
@@ -0,0 +1,36 @@
+package etcd
+
+import (
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func TestDecode(t *testing.T) {
+	// sorted like a range request
+	kvs := []*mvccpb.KeyValue{
+		{Key: []byte("/zmicro/config/example"), Value: []byte("http:\n  addr: :5180\nrpc:\n  addr: :5181\n")},
+		{Key: []byte("/zmicro/config/example/http/addr"), Value: []byte(`":5280"`)},
+		{Key: []byte("/zmicro/config/example/registry/etcdAddr"), Value: []byte("[a, b]")},
+		{Key: []byte("/zmicro/config/example2/http/addr"), Value: []byte(`":6180"`)},
+	}
+	got, err := decode("/zmicro/config/example", kvs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"http":     map[string]any{"addr": ":5280"},
+		"rpc":      map[string]any{"addr": ":5181"},
+		"registry": map[string]any{"etcdAddr": []any{"a", "b"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	if _, err = decode("/zmicro/config/example", []*mvccpb.KeyValue{
+		{Key: []byte("/zmicro/config/example/http"), Value: []byte("addr: [")},
+	}); err == nil {
+		t.Fatal("expected error for invalid yaml")
+	}
+}
@@ -0,0 +1,41 @@
+package etcd
+
+import "time"
+
+type Options struct {
+	Endpoints   []string
+	Prefix      string
+	DialTimeout time.Duration
+}
+
+type Option func(*Options)
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		DialTimeout: 5 * time.Second,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
+
+func Endpoints(a []string) Option {
+	return func(o *Options) {
+		o.Endpoints = a
+	}
+}
+
+func Prefix(s string) Option {
+	return func(o *Options) {
+		o.Prefix = s
+	}
+}
+
+func DialTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.DialTimeout = d
+	}
+}
@@ -1,9 +1,30 @@
 package config
 
 type Options struct {
-	Type      string
-	Path      string
-	Callbacks []func(IConfig)
+	Type string
+	// Path is the base config file.
+	Path string
+	// Paths are merged over Path in order.
+	Paths []string
+	// EnvFile merges the env specific file next to Path, e.g.
+	// config.develop.yaml for config.yaml, if it exists. The env is the
+	// app.mode of Path, or env.Get() if it is not set.
+	EnvFile bool
+	// EnvPrefix enables the environment variable overrides, e.g.
+	// ZMICRO_HTTP_ADDR overrides http.addr with prefix "ZMICRO". Unmarshal
+	// and Scan also set the fields of structs that are in no other layer,
+	// except under maps whose keys are not known.
+	EnvPrefix string
+	// Sources are merged over the files in order.
+	Sources []Source
+	// SecretResolvers resolve the secret references in the values.
+	// default EnvSecret and FileSecret
+	SecretResolvers []SecretResolver
+	// AutoReload reloads the config when a file or a source changes.
+	// default true
+	AutoReload bool
+	Callbacks  []func(IConfig)
+	OnChange   []ChangeFunc
 }
 
 type Option func(o *Options)
@@ -20,8 +41,52 @@ func Path(p string) Option {
 	}
 }
 
+func Paths(p ...string) Option {
+	return func(o *Options) {
+		o.Paths = append(o.Paths, p...)
+	}
+}
+
+func EnvFile(b bool) Option {
+	return func(o *Options) {
+		o.EnvFile = b
+	}
+}
+
+func EnvPrefix(s string) Option {
+	return func(o *Options) {
+		o.EnvPrefix = s
+	}
+}
+
+func Sources(s ...Source) Option {
+	return func(o *Options) {
+		o.Sources = append(o.Sources, s...)
+	}
+}
+
+// SecretResolvers adds secret resolvers, e.g. AESSecret.
+func SecretResolvers(r ...SecretResolver) Option {
+	return func(o *Options) {
+		o.SecretResolvers = append(o.SecretResolvers, r...)
+	}
+}
+
+func AutoReload(b bool) Option {
+	return func(o *Options) {
+		o.AutoReload = b
+	}
+}
+
 func Callbacks(f ...func(IConfig)) Option {
 	return func(o *Options) {
 		o.Callbacks = f
 	}
 }
+
+// OnChange adds funcs that can reject a reload, see Config.OnChange.
+func OnChange(f ...ChangeFunc) Option {
+	return func(o *Options) {
+		o.OnChange = append(o.OnChange, f...)
+	}
+}
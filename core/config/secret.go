@@ -0,0 +1,198 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// SecretResolver resolves the secret references in config values, e.g.
+// ${env:DB_PASS}. Resolved values are masked by Dump.
+type SecretResolver interface {
+	// Resolve returns the secret referenced by value, ok is false if value
+	// is not a reference handled by the resolver.
+	Resolve(value string) (secret string, ok bool, err error)
+}
+
+// SecretResolverFunc is an adapter to use a function as a SecretResolver.
+type SecretResolverFunc func(value string) (string, bool, error)
+
+func (f SecretResolverFunc) Resolve(value string) (string, bool, error) {
+	return f(value)
+}
+
+// EnvSecret resolves ${env:NAME} to the environment variable NAME,
+// which must be set.
+func EnvSecret() SecretResolver {
+	return refResolver("env", func(name string) (string, error) {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return v, nil
+	})
+}
+
+// FileSecret resolves ${file:PATH} to the content of the file PATH,
+// without the trailing newlines, e.g. a docker or kubernetes secret.
+func FileSecret() SecretResolver {
+	return refResolver("file", func(path string) (string, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	})
+}
+
+// refResolver resolves ${scheme:ref} with f.
+func refResolver(scheme string, f func(ref string) (string, error)) SecretResolver {
+	prefix := "${" + scheme + ":"
+	return SecretResolverFunc(func(value string) (string, bool, error) {
+		if !strings.HasPrefix(value, prefix) || !strings.HasSuffix(value, "}") {
+			return "", false, nil
+		}
+		s, err := f(value[len(prefix) : len(value)-1])
+		return s, true, err
+	})
+}
+
+const aesPrefix = "enc:AES256:"
+
+// AESSecret resolves enc:AES256:<base64> with the 32 bytes key, where the
+// decoded value is the 12 bytes nonce followed by the AES-256-GCM sealed
+// secret, see Encrypt.
+func AESSecret(key []byte) SecretResolver {
+	return SecretResolverFunc(func(value string) (string, bool, error) {
+		if !strings.HasPrefix(value, aesPrefix) {
+			return "", false, nil
+		}
+		gcm, err := newGCM(key)
+		if err != nil {
+			return "", true, err
+		}
+		b, err := base64.StdEncoding.DecodeString(value[len(aesPrefix):])
+		if err != nil {
+			return "", true, err
+		}
+		if len(b) < gcm.NonceSize() {
+			return "", true, errors.New("ciphertext too short")
+		}
+		s, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+		if err != nil {
+			return "", true, err
+		}
+		return string(s), true, nil
+	})
+}
+
+// Encrypt returns the enc:AES256:<base64> value of secret for AESSecret.
+func Encrypt(key []byte, secret string, nonce []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return "", fmt.Errorf("nonce must be %d bytes", gcm.NonceSize())
+	}
+	b := gcm.Seal(nonce[:len(nonce):len(nonce)], nonce, []byte(secret), nil)
+	return aesPrefix + base64.StdEncoding.EncodeToString(b), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("AES256 key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// resolveSecrets replaces the secret references in v, and returns the
+// resolved secrets.
+func resolveSecrets(v *viper.Viper, resolvers []SecretResolver) (map[string]struct{}, error) {
+	if len(resolvers) == 0 {
+		return nil, nil
+	}
+	r := &secretWalker{resolvers: resolvers, secrets: make(map[string]struct{})}
+	for key, val := range v.AllSettings() {
+		if err := r.walkKey(v, key, val); err != nil {
+			return nil, err
+		}
+	}
+	return r.secrets, nil
+}
+
+type secretWalker struct {
+	resolvers []SecretResolver
+	secrets   map[string]struct{}
+}
+
+// walkKey sets the maps leaves one by one, so the other layers of the
+// keys, e.g. environment variables, are kept.
+func (r *secretWalker) walkKey(v *viper.Viper, key string, val any) error {
+	if m, ok := val.(map[string]any); ok {
+		for k, vv := range m {
+			if err := r.walkKey(v, key+"."+k, vv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	resolved, changed, err := r.resolve(key, val)
+	if err != nil {
+		return err
+	}
+	if changed {
+		v.Set(key, resolved)
+	}
+	return nil
+}
+
+func (r *secretWalker) resolve(key string, val any) (any, bool, error) {
+	switch vv := val.(type) {
+	case string:
+		for _, res := range r.resolvers {
+			s, ok, err := res.Resolve(vv)
+			if err != nil {
+				return nil, false, fmt.Errorf("config: resolve secret %s: %w", key, err)
+			}
+			if ok {
+				if s != "" {
+					r.secrets[s] = struct{}{}
+				}
+				return s, true, nil
+			}
+		}
+	case map[string]any:
+		// copy, the value may be shared with a layer
+		out, changed := make(map[string]any, len(vv)), false
+		for k, e := range vv {
+			s, ok, err := r.resolve(key+"."+k, e)
+			if err != nil {
+				return nil, false, err
+			}
+			out[k], changed = s, changed || ok
+		}
+		return out, changed, nil
+	case []any:
+		out, changed := make([]any, len(vv)), false
+		for i, e := range vv {
+			s, ok, err := r.resolve(fmt.Sprintf("%s.%d", key, i), e)
+			if err != nil {
+				return nil, false, err
+			}
+			out[i], changed = s, changed || ok
+		}
+		return out, changed, nil
+	}
+	return val, false, nil
+}
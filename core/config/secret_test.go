@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecrets(t *testing.T) {
+	dir := t.TempDir()
+	key := []byte(strings.Repeat("k", 32))
+	enc, err := Encrypt(key, "aes-secret", make([]byte, 12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretFile := filepath.Join(dir, "secret")
+	if err = os.WriteFile(secretFile, []byte("file-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ZMTEST_DB_PASS", "env-secret")
+
+	p := filepath.Join(dir, "config.yaml")
+	content := `
+db:
+  dsn: ${env:ZMTEST_DB_PASS}
+clients:
+  - id: web
+    key: ${file:` + secretFile + `}
+cipher: ` + enc + `
+`
+	if err = os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(Path(p), AutoReload(false), SecretResolvers(AESSecret(key)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.GetString("db.dsn"); got != "env-secret" {
+		t.Errorf("db.dsn = %q", got)
+	}
+	if got := c.GetString("cipher"); got != "aes-secret" {
+		t.Errorf("cipher = %q", got)
+	}
+	var clients []struct{ ID, Key string }
+	if err = c.Scan("clients", &clients); err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 1 || clients[0].Key != "file-secret" {
+		t.Errorf("clients = %+v", clients)
+	}
+
+	s := c.(*Config).String()
+	for _, secret := range []string{"env-secret", "file-secret", "aes-secret"} {
+		if strings.Contains(s, secret) {
+			t.Errorf("%q is not masked in %s", secret, s)
+		}
+	}
+
+	t.Setenv("ZMTEST_DB_PASS", "")
+	os.Unsetenv("ZMTEST_DB_PASS")
+	if _, err = Load(Path(p), AutoReload(false), SecretResolvers(AESSecret(key))); err == nil {
+		t.Fatal("expected error for unset env")
+	}
+}
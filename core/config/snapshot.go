@@ -0,0 +1,89 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Snapshot is an immutable version of a Config. The maps and slices
+// returned by its getters must not be modified.
+type Snapshot struct {
+	v         *viper.Viper
+	data      []byte
+	secrets   map[string]struct{}
+	version   uint64
+	envPrefix string
+}
+
+// Version returns the version of the snapshot, see Config.Version.
+func (s *Snapshot) Version() uint64 {
+	return s.version
+}
+
+func (s *Snapshot) isSecret(value string) bool {
+	_, ok := s.secrets[value]
+	return ok
+}
+
+// String returns the settings as JSON, masked like Dump.
+func (s *Snapshot) String() string {
+	m := make(map[string]any)
+	if err := s.Unmarshal(&m); err != nil {
+		return err.Error()
+	}
+	b, _ := json.Marshal(dumper{isSecret: s.isSecret}.maskMap(m))
+	return string(b)
+}
+
+func (s *Snapshot) Unmarshal(val any) error {
+	if err := s.v.Unmarshal(val); err != nil {
+		return err
+	}
+	return unmarshalEnv(s.envPrefix, "", val)
+}
+
+func (s *Snapshot) Scan(key string, val any) error {
+	if err := s.v.UnmarshalKey(key, val); err != nil {
+		return err
+	}
+	return unmarshalEnv(s.envPrefix, strings.ToLower(key), val)
+}
+
+func (s *Snapshot) Get(key string) any {
+	return s.v.Get(key)
+}
+
+func (s *Snapshot) GetString(key string) string {
+	return s.v.GetString(key)
+}
+
+func (s *Snapshot) GetBool(key string) bool {
+	return s.v.GetBool(key)
+}
+
+func (s *Snapshot) GetInt(key string) int {
+	return s.v.GetInt(key)
+}
+
+func (s *Snapshot) GetFloat64(key string) float64 {
+	return s.v.GetFloat64(key)
+}
+
+func (s *Snapshot) GetDuration(key string) time.Duration {
+	return s.v.GetDuration(key)
+}
+
+func (s *Snapshot) GetIntSlice(key string) []int {
+	return s.v.GetIntSlice(key)
+}
+
+func (s *Snapshot) GetStringSlice(key string) []string {
+	return s.v.GetStringSlice(key)
+}
+
+func (s *Snapshot) GetStringMap(key string) map[string]any {
+	return s.v.GetStringMap(key)
+}
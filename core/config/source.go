@@ -0,0 +1,36 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zmicro-team/zmicro/core/util/env"
+)
+
+// Source provides settings that are merged over the config files.
+type Source interface {
+	// Load returns the current settings of the source.
+	Load() (map[string]any, error)
+	// Watch calls onChange whenever the settings of the source change,
+	// until Close is called.
+	Watch(onChange func()) error
+	// Close stops watching.
+	Close() error
+}
+
+// envFile returns the env specific file of path, e.g. config.develop.yaml
+// for config.yaml. mode is the app.mode of the base file, env.Get() is
+// used if it is empty.
+func envFile(path, mode string) string {
+	if mode == "" {
+		mode = env.Get().String()
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + strings.ToLower(mode) + ext
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -84,6 +84,22 @@ func ErrConflictf(format string, args ...any) *Error {
 	return Newf(409, "资源冲突", fmt.Sprintf(format, args...))
 }
 
+// IsTooManyRequests determines if err is an error which indicates a TooManyRequests error.
+// It supports wrapped errors.
+func IsTooManyRequests(err error) bool {
+	return Code(err) == 429
+}
+
+// ErrTooManyRequests new TooManyRequests error that is mapped to a 429 response.
+func ErrTooManyRequests(detail string) *Error {
+	return New(429, "请求过于频繁", detail)
+}
+
+// ErrTooManyRequestsf new TooManyRequests error that is mapped to a 429 response.
+func ErrTooManyRequestsf(format string, args ...any) *Error {
+	return Newf(429, "请求过于频繁", format, args...)
+}
+
 func IsInternalServer(err error) bool {
 	return Code(err) == 500
 }
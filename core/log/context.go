@@ -0,0 +1,75 @@
+package log
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/zmicro-team/zmicro/core/transport"
+)
+
+// RequestIdHeader is the transport header of the request id.
+const RequestIdHeader = "X-Request-Id"
+
+type ctxFieldsKey struct{}
+
+// NewContext returns a copy of ctx with fields added to the ones already
+// in ctx, they are logged by the logger of FromContext.
+func NewContext(ctx context.Context, fields ...Field) context.Context {
+	old := fieldsFromContext(ctx)
+	fs := make([]Field, 0, len(old)+len(fields))
+	fs = append(fs, old...)
+	fs = append(fs, fields...)
+	return context.WithValue(ctx, ctxFieldsKey{}, fs)
+}
+
+func fieldsFromContext(ctx context.Context) []Field {
+	fs, _ := ctx.Value(ctxFieldsKey{}).([]Field)
+	return fs
+}
+
+// FromContext returns the default logger with the fields of ctx, see
+// Logger.FromContext.
+func FromContext(ctx context.Context) *Logger {
+	return Default().FromContext(ctx)
+}
+
+// Ctx is short for FromContext.
+func Ctx(ctx context.Context) *Logger {
+	return FromContext(ctx)
+}
+
+// FromContext returns a child logger with the fields of ctx:
+//   - trace_id and span_id of the OpenTelemetry span
+//   - request_id from the RequestIdHeader of the transport
+//   - the fields stored by NewContext
+func (l *Logger) FromContext(ctx context.Context) *Logger {
+	fields := ContextFields(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return l.With(fields...)
+}
+
+// ContextFields returns the fields of ctx logged by FromContext.
+func ContextFields(ctx context.Context) []Field {
+	stored := fieldsFromContext(ctx)
+	fields := make([]Field, 0, 3+len(stored))
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields,
+			zap.String("trace_id", sc.TraceID().String()),
+			zap.String("span_id", sc.SpanID().String()),
+		)
+	}
+	if tr, ok := transport.FromTransporter(ctx); ok {
+		id := tr.RequestHeader().Get(RequestIdHeader)
+		if id == "" {
+			id = tr.ResponseHeader().Get(RequestIdHeader)
+		}
+		if id != "" {
+			fields = append(fields, zap.String("request_id", id))
+		}
+	}
+	return append(fields, stored...)
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/zmicro-team/zmicro/core/transport"
+)
+
+type testHeader http.Header
+
+func (h testHeader) Get(key string) string        { return http.Header(h).Get(key) }
+func (h testHeader) Add(key, value string)        { http.Header(h).Add(key, value) }
+func (h testHeader) Set(key string, value string) { http.Header(h).Set(key, value) }
+func (h testHeader) Keys() []string               { return nil }
+func (h testHeader) Clone() transport.Header      { return h }
+
+type testTransporter struct{ header testHeader }
+
+func (*testTransporter) Kind() transport.Kind               { return transport.HTTP }
+func (*testTransporter) FullPath() string                   { return "" }
+func (*testTransporter) ClientIp() string                   { return "" }
+func (t *testTransporter) RequestHeader() transport.Header  { return t.header }
+func (t *testTransporter) ResponseHeader() transport.Header { return testHeader{} }
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, InfoLevel)
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{2},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+	tr := &testTransporter{header: testHeader{}}
+	tr.header.Set(RequestIdHeader, "req-1")
+	ctx = transport.WithValueTransporter(ctx, tr)
+	ctx = NewContext(ctx, zap.String("user", "u1"))
+	ctx = NewContext(ctx, zap.Int("order", 7))
+
+	l.FromContext(ctx).Info("hello")
+
+	out := buf.String()
+	for _, want := range []string{
+		`"trace_id":"` + sc.TraceID().String() + `"`,
+		`"span_id":"` + sc.SpanID().String() + `"`,
+		`"request_id":"req-1"`,
+		`"user":"u1"`,
+		`"order":7`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("%s does not contain %s", out, want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type samplingOptions struct {
+	tick       time.Duration
+	first      int
+	thereafter int
+}
+
+type rateLimitOptions struct {
+	rate  float64
+	burst int
+}
+
+// limitCore wraps core with the dedup, rate limit and sampling options of
+// l, in that order, so repeated messages are counted before being dropped.
+func (l *Logger) limitCore(core zapcore.Core) zapcore.Core {
+	if s := l.sampling; s != nil {
+		core = zapcore.NewSamplerWithOptions(core, s.tick, s.first, s.thereafter)
+	}
+	if r := l.rateLimit; r != nil {
+		core = &rateLimitCore{Core: core, limiter: newMessageLimiter(r.rate, r.burst)}
+	}
+	if l.dedup > 0 {
+		core = &dedupCore{Core: core, state: &dedupState{window: l.dedup}}
+	}
+	return core
+}
+
+// maxBuckets bounds the messages tracked by a messageLimiter, when it is
+// reached the buckets are reset.
+const maxBuckets = 4096
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// messageLimiter is a token bucket per level and message.
+type messageLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[messageKey]*bucket
+}
+
+type messageKey struct {
+	level   zapcore.Level
+	message string
+}
+
+func newMessageLimiter(rate float64, burst int) *messageLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &messageLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[messageKey]*bucket),
+	}
+}
+
+func (m *messageLimiter) allow(k messageKey, now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.buckets[k]
+	if !ok {
+		if len(m.buckets) >= maxBuckets {
+			m.buckets = make(map[messageKey]*bucket)
+		}
+		b = &bucket{tokens: m.burst, last: now}
+		m.buckets[k] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * m.rate
+	if b.tokens > m.burst {
+		b.tokens = m.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// rateLimitCore drops the entries of a message over its rate.
+type rateLimitCore struct {
+	zapcore.Core
+	limiter *messageLimiter
+}
+
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rateLimitCore{Core: c.Core.With(fields), limiter: c.limiter}
+}
+
+func (c *rateLimitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	if !c.limiter.allow(messageKey{ent.Level, ent.Message}, ent.Time) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+type dedupState struct {
+	mu     sync.Mutex
+	window time.Duration
+	last   zapcore.Entry
+	core   zapcore.Core
+	count  int
+}
+
+// dedupCore collapses a message repeated in a row within the window into
+// one entry with a "repeated" field, written when another message is
+// logged, or on Sync.
+type dedupCore struct {
+	zapcore.Core
+	state *dedupState
+}
+
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupCore{Core: c.Core.With(fields), state: c.state}
+}
+
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+
+	s := c.state
+	s.mu.Lock()
+	if ent.Level == s.last.Level && ent.Message == s.last.Message &&
+		ent.Time.Sub(s.last.Time) < s.window {
+		s.count++
+		s.mu.Unlock()
+		return ce
+	}
+	s.flush()
+	s.last, s.core = ent, c.Core
+	s.mu.Unlock()
+
+	return c.Core.Check(ent, ce)
+}
+
+func (c *dedupCore) Sync() error {
+	c.state.mu.Lock()
+	c.state.flush()
+	c.state.mu.Unlock()
+	return c.Core.Sync()
+}
+
+// flush writes the repeated count of the last message, s.mu must be held.
+func (s *dedupState) flush() {
+	if s.count == 0 {
+		return
+	}
+	ent := s.last
+	ent.Time = time.Now()
+	if ce := s.core.Check(ent, nil); ce != nil {
+		ce.Write(zap.Int("repeated", s.count))
+	}
+	s.count = 0
+}
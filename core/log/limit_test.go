@@ -0,0 +1,42 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, InfoLevel, WithRateLimit(0.001, 2))
+	for i := 0; i < 10; i++ {
+		l.Info("loop")
+	}
+	l.Info("other")
+	if got := strings.Count(buf.String(), `"msg":"loop"`); got != 2 {
+		t.Fatalf("loop logged %d times, want 2", got)
+	}
+	if !strings.Contains(buf.String(), `"msg":"other"`) {
+		t.Fatal("other message is dropped")
+	}
+}
+
+func TestDedup(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, InfoLevel, WithDedup(time.Minute))
+	for i := 0; i < 5; i++ {
+		l.Error("boom")
+	}
+	l.Info("done")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[1], `"msg":"boom"`) || !strings.Contains(lines[1], `"repeated":4`) {
+		t.Fatalf("unexpected summary: %s", lines[1])
+	}
+	if !strings.Contains(lines[2], `"msg":"done"`) {
+		t.Fatalf("unexpected last line: %s", lines[2])
+	}
+}
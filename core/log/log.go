@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"time"
 
 	"go.uber.org/zap"
@@ -21,9 +22,22 @@ type Logger struct {
 	callSkip    int
 	fn          []Valuer
 	ctx         context.Context
+	sampling    *samplingOptions
+	rateLimit   *rateLimitOptions
+	dedup       time.Duration
 }
 
 func NewTee(writers []io.Writer, level Level, opts ...Option) *Logger {
+	sinks := make([]Sink, 0, len(writers))
+	for _, w := range writers {
+		sinks = append(sinks, Sink{Writer: w})
+	}
+	return NewWithSinks(sinks, level, opts...)
+}
+
+// NewWithSinks creates a Logger that writes to all sinks. level is the
+// minimum level of all sinks and can be changed by SetLevel.
+func NewWithSinks(sinks []Sink, level Level, opts ...Option) *Logger {
 	logger := &Logger{callSkip: 1, ctx: context.Background()}
 	lv := zap.NewAtomicLevelAt(level)
 	logger.lv = &lv
@@ -38,19 +52,13 @@ func NewTee(writers []io.Writer, level Level, opts ...Option) *Logger {
 	cfg.EncoderConfig.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 		enc.AppendString(t.Format("2006-01-02T15:04:05.000Z0700"))
 	}
-	var enc zapcore.Encoder
-	if logger.development {
-		enc = zapcore.NewConsoleEncoder(cfg.EncoderConfig)
-	} else {
-		enc = zapcore.NewJSONEncoder(cfg.EncoderConfig)
-	}
 
 	var cores []zapcore.Core
-	for _, w := range writers {
+	for _, s := range sinks {
 		core := zapcore.NewCore(
-			enc,
-			zapcore.AddSync(w),
-			lv,
+			logger.encoder(s.Encoding, cfg.EncoderConfig),
+			zapcore.AddSync(s.Writer),
+			s.levelEnabler(lv),
 		)
 		cores = append(cores, core)
 	}
@@ -62,7 +70,7 @@ func NewTee(writers []io.Writer, level Level, opts ...Option) *Logger {
 	options = append(options, zap.AddCallerSkip(logger.callSkip))
 
 	logger.l = zap.New(
-		zapcore.NewTee(cores...),
+		logger.limitCore(zapcore.NewTee(cores...)),
 		options...,
 	)
 
@@ -88,6 +96,18 @@ func (l *Logger) SetLevel(lv Level) {
 	l.lv.SetLevel(lv)
 }
 
+// LevelHandler returns an http.Handler that reports the level with GET and
+// changes it with PUT, e.g. curl -X PUT -d '{"level":"debug"}'.
+// See zap.AtomicLevel.ServeHTTP.
+func (l *Logger) LevelHandler() http.Handler {
+	return l.lv
+}
+
+// Level returns the minimum enabled log level.
+func (l *Logger) Level() Level {
+	return l.lv.Level()
+}
+
 // Enabled returns true if the given level is at or above this level.
 func (l *Logger) Enabled(lvl zapcore.Level) bool {
 	return l.lv.Enabled(lvl)
@@ -104,68 +124,38 @@ func (l *Logger) WithValuer(fs ...Valuer) *Logger {
 	fn := make([]Valuer, 0, len(fs)+len(l.fn))
 	fn = append(fn, l.fn...)
 	fn = append(fn, fs...)
-	return &Logger{
-		l.l,
-		l.lv,
-		l.development,
-		l.addCaller,
-		l.callSkip,
-		fn,
-		l.ctx,
-	}
+	nl := *l
+	nl.fn = fn
+	return &nl
 }
 
 // WithNewValuer return log with new Valuer function without default Valuer.
 func (l *Logger) WithNewValuer(fs ...Valuer) *Logger {
-	return &Logger{
-		l.l,
-		l.lv,
-		l.development,
-		l.addCaller,
-		l.callSkip,
-		fs,
-		l.ctx,
-	}
+	nl := *l
+	nl.fn = fs
+	return &nl
 }
 
 // WithContext return log with inject context.
 func (l *Logger) WithContext(ctx context.Context) *Logger {
-	return &Logger{
-		l.l,
-		l.lv,
-		l.development,
-		l.addCaller,
-		l.callSkip,
-		l.fn,
-		ctx,
-	}
+	nl := *l
+	nl.ctx = ctx
+	return &nl
 }
 
 // With creates a child logger and adds structured context to it. Fields added
 // to the child don't affect the parent, and vice versa.
 func (l *Logger) With(fields ...Field) *Logger {
-	return &Logger{
-		l.l.With(fields...),
-		l.lv,
-		l.development,
-		l.addCaller,
-		l.callSkip,
-		l.fn,
-		l.ctx,
-	}
+	nl := *l
+	nl.l = l.l.With(fields...)
+	return &nl
 }
 
 // Named adds a sub-scope to the logger's name. See Log.Named for details.
 func (l *Logger) Named(name string) *Logger {
-	return &Logger{
-		l.l.Named(name),
-		l.lv,
-		l.development,
-		l.addCaller,
-		l.callSkip,
-		l.fn,
-		l.ctx,
-	}
+	nl := *l
+	nl.l = l.l.Named(name)
+	return &nl
 }
 
 func (l *Logger) Debug(v any, fields ...Field) {
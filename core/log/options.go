@@ -1,5 +1,9 @@
 package log
 
+import (
+	"time"
+)
+
 type Option interface {
 	apply(logger *Logger)
 }
@@ -38,3 +42,27 @@ func WithDefaultValuer(vs ...Valuer) Option {
 		}
 	})
 }
+
+// WithSampling logs the first entries with the same level and message in
+// each tick, then every thereafter-th entry. See zapcore.NewSamplerWithOptions.
+func WithSampling(tick time.Duration, first, thereafter int) Option {
+	return optionFunc(func(l *Logger) {
+		l.sampling = &samplingOptions{tick: tick, first: first, thereafter: thereafter}
+	})
+}
+
+// WithRateLimit limits the entries with the same level and message to rate
+// per second, with bursts of burst entries. The others are dropped.
+func WithRateLimit(rate float64, burst int) Option {
+	return optionFunc(func(l *Logger) {
+		l.rateLimit = &rateLimitOptions{rate: rate, burst: burst}
+	})
+}
+
+// WithDedup collapses the same message logged in a row within window into
+// one entry with a "repeated" field holding the number of dropped entries.
+func WithDedup(window time.Duration) Option {
+	return optionFunc(func(l *Logger) {
+		l.dedup = window
+	})
+}
@@ -0,0 +1,52 @@
+package log
+
+import (
+	"io"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Encodings of a Sink.
+const (
+	EncodingJSON    = "json"
+	EncodingConsole = "console"
+	// EncodingColor is console with colored levels, for terminals.
+	EncodingColor = "color"
+)
+
+// Sink is a destination of a Logger, see NewWithSinks.
+type Sink struct {
+	Writer io.Writer
+	// Level is the minimum level of the sink, in addition to the level of
+	// the Logger, e.g. WarnLevel. nil means all levels.
+	Level zapcore.LevelEnabler
+	// Encoding is one of json, console and color. default, also for the
+	// unknown encodings, console with Development, json otherwise
+	Encoding string
+}
+
+func (s Sink) levelEnabler(lv zap.AtomicLevel) zapcore.LevelEnabler {
+	if s.Level == nil {
+		return lv
+	}
+	return zap.LevelEnablerFunc(func(l Level) bool {
+		return lv.Enabled(l) && s.Level.Enabled(l)
+	})
+}
+
+func (l *Logger) encoder(encoding string, cfg zapcore.EncoderConfig) zapcore.Encoder {
+	switch encoding {
+	case EncodingJSON:
+		return zapcore.NewJSONEncoder(cfg)
+	case EncodingConsole:
+		return zapcore.NewConsoleEncoder(cfg)
+	case EncodingColor:
+		cfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		return zapcore.NewConsoleEncoder(cfg)
+	}
+	if l.development {
+		return zapcore.NewConsoleEncoder(cfg)
+	}
+	return zapcore.NewJSONEncoder(cfg)
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSinks(t *testing.T) {
+	var console, file bytes.Buffer
+	l := NewWithSinks([]Sink{
+		{Writer: &console, Encoding: EncodingConsole},
+		{Writer: &file, Level: WarnLevel, Encoding: EncodingJSON},
+	}, DebugLevel)
+
+	l.Debug("debug")
+	l.Warn("warn")
+
+	if c := console.String(); !strings.Contains(c, "debug") || !strings.Contains(c, "warn") ||
+		strings.HasPrefix(c, "{") {
+		t.Fatalf("unexpected console output: %s", c)
+	}
+	if f := file.String(); strings.Contains(f, "debug") || !strings.Contains(f, `"msg":"warn"`) {
+		t.Fatalf("unexpected file output: %s", f)
+	}
+
+	// the logger level applies to all sinks
+	l.SetLevel(ErrorLevel)
+	l.Warn("dropped")
+	if strings.Contains(console.String()+file.String(), "dropped") {
+		t.Fatal("warn logged above the logger level")
+	}
+}
+
+func TestSinkUnknownEncoding(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWithSinks([]Sink{{Writer: &buf, Encoding: "xml"}}, InfoLevel)
+	l.Info("hello")
+	if !strings.Contains(buf.String(), `"msg":"hello"`) {
+		t.Fatalf("got %s, want the json default", buf.String())
+	}
+}
@@ -0,0 +1,133 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SetSlogDefault makes l the default slog logger, and so the output of the
+// standard log package too.
+func SetSlogDefault(l *Logger) {
+	slog.SetDefault(slog.New(l.Handler()))
+}
+
+// Handler returns an slog.Handler that writes to l, with its level, its
+// valuers and the fields of the context, see FromContext.
+func (l *Logger) Handler() slog.Handler {
+	return &slogHandler{l: l}
+}
+
+type slogHandler struct {
+	l *Logger
+	// fields of WithAttrs, and zap.Namespace for WithGroup
+	fields []Field
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.l.lv.Enabled(zapLevel(level))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ce := h.l.l.Check(zapLevel(r.Level), r.Message)
+	if ce == nil {
+		return nil
+	}
+	if !r.Time.IsZero() {
+		ce.Time = r.Time
+	}
+	// the caller of zap is in slog
+	if ce.Caller.Defined && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ce.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+
+	if ctx == nil {
+		ctx = h.l.ctx
+	}
+	fields := injectFields(ctx, h.l.fn)
+	fields = append(fields, ContextFields(ctx)...)
+	fields = append(fields, h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, a)
+		return true
+	})
+	ce.Write(fields...)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make([]Field, 0, len(h.fields)+len(attrs))
+	fields = append(fields, h.fields...)
+	for _, a := range attrs {
+		fields = appendAttr(fields, a)
+	}
+	return &slogHandler{l: h.l, fields: fields}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	fields := make([]Field, 0, len(h.fields)+1)
+	fields = append(fields, h.fields...)
+	fields = append(fields, zap.Namespace(name))
+	return &slogHandler{l: h.l, fields: fields}
+}
+
+func zapLevel(l slog.Level) Level {
+	switch {
+	case l < slog.LevelInfo:
+		return DebugLevel
+	case l < slog.LevelWarn:
+		return InfoLevel
+	case l < slog.LevelError:
+		return WarnLevel
+	default:
+		return ErrorLevel
+	}
+}
+
+func appendAttr(fields []Field, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindGroup:
+		attrs := v.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		var group []Field
+		for _, ga := range attrs {
+			group = appendAttr(group, ga)
+		}
+		// an empty key inlines the group
+		if a.Key == "" {
+			return append(fields, group...)
+		}
+		return append(fields, zap.Dict(a.Key, group...))
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, v.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, v.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, v.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, v.Time()))
+	default:
+		return append(fields, zap.Any(a.Key, v.Any()))
+	}
+}
@@ -0,0 +1,39 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, InfoLevel, WithCaller(true)).
+		WithValuer(ImmutString("app", "test"))
+	sl := slog.New(l.Handler())
+
+	sl.Debug("dropped")
+	ctx := NewContext(context.Background(), zap.String("user", "u1"))
+	sl.With("a", 1).WithGroup("g").InfoContext(ctx, "hello", "b", "x", slog.Group("c", "d", true))
+
+	out := buf.String()
+	if strings.Contains(out, "dropped") {
+		t.Fatalf("debug logged at info level: %s", out)
+	}
+	for _, want := range []string{
+		`"msg":"hello"`,
+		`"app":"test"`,
+		`"user":"u1"`,
+		`"a":1`,
+		`"g":{"b":"x","c":{"d":true}}`,
+		`"caller":"log/slog_test.go:`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("%s does not contain %s", out, want)
+		}
+	}
+}
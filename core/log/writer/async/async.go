@@ -0,0 +1,194 @@
+package async
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	defaultQueueSize = 1024
+)
+
+// ErrClosed is returned by Write after Close.
+var ErrClosed = errors.New("async: writer closed")
+
+// DropPolicy is what Write does when the queue is full.
+type DropPolicy int
+
+const (
+	// Block waits for room in the queue.
+	Block DropPolicy = iota
+	// DropNew drops the entry being written.
+	DropNew
+	// DropOldest drops the oldest entry in the queue.
+	DropOldest
+)
+
+type Options struct {
+	QueueSize  int
+	DropPolicy DropPolicy
+}
+
+type Option func(*Options)
+
+type entry struct {
+	p []byte
+	// flush is closed when the entries before it are written
+	flush chan struct{}
+}
+
+// Writer writes to the underlying writer in a goroutine, through a bounded
+// queue, so logging does not wait for a slow writer.
+type Writer struct {
+	w       io.Writer
+	opts    Options
+	queue   chan entry
+	done    chan struct{}
+	dropped atomic.Uint64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewWriter(w io.Writer, opts ...Option) *Writer {
+	options := Options{
+		QueueSize: defaultQueueSize,
+	}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = defaultQueueSize
+	}
+
+	aw := &Writer{
+		w:     w,
+		opts:  options,
+		queue: make(chan entry, options.QueueSize),
+		done:  make(chan struct{}),
+	}
+	go aw.run()
+	return aw
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	for e := range w.queue {
+		if e.flush != nil {
+			close(e.flush)
+			continue
+		}
+		_, _ = w.w.Write(e.p)
+	}
+}
+
+// Write queues a copy of p, it never returns the error of the underlying
+// writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	e := entry{p: append([]byte(nil), p...)}
+	switch w.opts.DropPolicy {
+	case DropNew:
+		select {
+		case w.queue <- e:
+		default:
+			w.dropped.Add(1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case w.queue <- e:
+				return len(p), nil
+			default:
+			}
+			select {
+			case old := <-w.queue:
+				if old.flush != nil {
+					// keep the flush marker, it is not an entry
+					close(old.flush)
+				} else {
+					w.dropped.Add(1)
+				}
+			default:
+			}
+		}
+	default:
+		w.queue <- e
+	}
+	return len(p), nil
+}
+
+// Flush waits for the queued entries to be written, and syncs the
+// underlying writer if it is a zapcore.WriteSyncer.
+func (w *Writer) Flush() error {
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return nil
+	}
+	flush := make(chan struct{})
+	w.queue <- entry{flush: flush}
+	w.mu.RUnlock()
+
+	<-flush
+	return w.sync()
+}
+
+// Sync is Flush, for zapcore.WriteSyncer.
+func (w *Writer) Sync() error {
+	return w.Flush()
+}
+
+// Close flushes the writer and stops it, the underlying writer is closed
+// if it is an io.Closer.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+	err := w.sync()
+	if c, ok := w.w.(io.Closer); ok {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Dropped returns the number of entries dropped by the DropPolicy.
+func (w *Writer) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+func (w *Writer) sync() error {
+	if s, ok := w.w.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func QueueSize(n int) Option {
+	return func(o *Options) {
+		o.QueueSize = n
+	}
+}
+
+func Drop(p DropPolicy) Option {
+	return func(o *Options) {
+		o.DropPolicy = p
+	}
+}
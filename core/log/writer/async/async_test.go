@@ -0,0 +1,57 @@
+package async
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func TestFlushAndClose(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	close(bw.release)
+	w := NewWriter(bw)
+	for i := 0; i < 100; i++ {
+		_, _ = w.Write([]byte("x\n"))
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(bw.buf.Bytes(), []byte("x")); n != 100 {
+		t.Fatalf("written %d, want 100", n)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("x")); err != ErrClosed {
+		t.Fatalf("err = %v, want ErrClosed", err)
+	}
+}
+
+func TestDropNew(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	w := NewWriter(bw, QueueSize(2), Drop(DropNew))
+	for i := 0; i < 10; i++ {
+		_, _ = w.Write([]byte("x"))
+	}
+	// one is being written, two are queued
+	if d := w.Dropped(); d < 7 {
+		t.Fatalf("dropped %d, want at least 7", d)
+	}
+	close(bw.release)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
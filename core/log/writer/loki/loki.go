@@ -0,0 +1,293 @@
+package loki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultURL        = "http://127.0.0.1:3100/loki/api/v1/push"
+	defaultBatchSize  = 100
+	defaultBatchWait  = time.Second
+	defaultMaxRetries = 3
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultTimeout    = 5 * time.Second
+	// the lines buffered at most, in batches
+	maxBufferedBatches = 10
+)
+
+type Options struct {
+	// URL is the push endpoint.
+	URL    string
+	Labels map[string]string
+	// Header is added to the requests, e.g. X-Scope-OrgID.
+	Header http.Header
+	// BatchSize lines are pushed at once, or the lines of BatchWait.
+	BatchSize  int
+	BatchWait  time.Duration
+	MaxRetries int
+	MinBackoff time.Duration
+	Timeout    time.Duration
+	Client     *http.Client
+}
+
+type Option func(*Options)
+
+type line struct {
+	t time.Time
+	s string
+}
+
+// Writer pushes the lines to Loki, or a compatible collector, in batches
+// with retry. When the collector is down, the lines over 10 batches are
+// dropped.
+type Writer struct {
+	opts    Options
+	dropped atomic.Uint64
+
+	mu    sync.Mutex
+	lines []line
+
+	pushMu sync.Mutex
+	full   chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewWriter(opts ...Option) *Writer {
+	options := Options{
+		URL:        defaultURL,
+		BatchSize:  defaultBatchSize,
+		BatchWait:  defaultBatchWait,
+		MaxRetries: defaultMaxRetries,
+		MinBackoff: defaultMinBackoff,
+		Timeout:    defaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.Client == nil {
+		options.Client = &http.Client{Timeout: options.Timeout}
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultBatchSize
+	}
+
+	w := &Writer{
+		opts: options,
+		full: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.opts.BatchWait)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		case <-w.full:
+		}
+		_ = w.Flush()
+	}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	s := string(bytes.TrimRight(p, "\n"))
+
+	w.mu.Lock()
+	if len(w.lines) >= maxBufferedBatches*w.opts.BatchSize {
+		w.mu.Unlock()
+		w.dropped.Add(1)
+		return len(p), nil
+	}
+	w.lines = append(w.lines, line{t: time.Now(), s: s})
+	n := len(w.lines)
+	w.mu.Unlock()
+
+	if n >= w.opts.BatchSize {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Flush pushes the buffered lines, the lines of a batch that cannot be
+// pushed after the retries are dropped.
+func (w *Writer) Flush() error {
+	w.pushMu.Lock()
+	defer w.pushMu.Unlock()
+
+	var err error
+	for {
+		w.mu.Lock()
+		n := len(w.lines)
+		if n > w.opts.BatchSize {
+			n = w.opts.BatchSize
+		}
+		batch := w.lines[:n:n]
+		w.lines = w.lines[n:]
+		w.mu.Unlock()
+		if n == 0 {
+			return err
+		}
+		if e := w.push(batch); e != nil {
+			w.dropped.Add(uint64(n))
+			err = e
+		}
+	}
+}
+
+// Sync is Flush, for zapcore.WriteSyncer.
+func (w *Writer) Sync() error {
+	return w.Flush()
+}
+
+// Close stops the writer and flushes it.
+func (w *Writer) Close() error {
+	w.once.Do(func() {
+		close(w.stop)
+		<-w.done
+	})
+	return w.Flush()
+}
+
+// Dropped returns the number of lines dropped.
+func (w *Writer) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+type pushRequest struct {
+	Streams []stream `json:"streams"`
+}
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (w *Writer) push(batch []line) error {
+	s := stream{Stream: w.opts.Labels, Values: make([][2]string, 0, len(batch))}
+	if s.Stream == nil {
+		s.Stream = map[string]string{}
+	}
+	for _, l := range batch {
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(l.t.UnixNano(), 10), l.s})
+	}
+	body, err := json.Marshal(pushRequest{Streams: []stream{s}})
+	if err != nil {
+		return err
+	}
+
+	backoff := w.opts.MinBackoff
+	for i := 0; ; i++ {
+		var retry bool
+		if retry, err = w.post(body); err == nil || !retry || i >= w.opts.MaxRetries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-w.stop:
+			// closing, retry once more without waiting
+		}
+		backoff *= 2
+	}
+}
+
+// post returns whether to retry on error.
+func (w *Writer) post(body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for k, v := range w.opts.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.opts.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = fmt.Errorf("loki: push: %s", resp.Status)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+func URL(s string) Option {
+	return func(o *Options) {
+		o.URL = s
+	}
+}
+
+func Labels(m map[string]string) Option {
+	return func(o *Options) {
+		o.Labels = m
+	}
+}
+
+func Header(h http.Header) Option {
+	return func(o *Options) {
+		o.Header = h
+	}
+}
+
+func BatchSize(n int) Option {
+	return func(o *Options) {
+		o.BatchSize = n
+	}
+}
+
+func BatchWait(d time.Duration) Option {
+	return func(o *Options) {
+		o.BatchWait = d
+	}
+}
+
+func MaxRetries(n int) Option {
+	return func(o *Options) {
+		o.MaxRetries = n
+	}
+}
+
+func MinBackoff(d time.Duration) Option {
+	return func(o *Options) {
+		o.MinBackoff = d
+	}
+}
+
+func Timeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+func Client(c *http.Client) Option {
+	return func(o *Options) {
+		o.Client = c
+	}
+}
@@ -0,0 +1,53 @@
+package loki
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPushWithRetry(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+		lines []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var req pushRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		if req.Streams[0].Stream["app"] != "test" {
+			t.Errorf("unexpected labels: %v", req.Streams[0].Stream)
+		}
+		for _, v := range req.Streams[0].Values {
+			lines = append(lines, v[1])
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	w := NewWriter(URL(srv.URL), Labels(map[string]string{"app": "test"}),
+		BatchWait(time.Hour), MinBackoff(time.Millisecond))
+	_, _ = w.Write([]byte("a\n"))
+	_, _ = w.Write([]byte("b\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 || len(lines) != 2 || lines[0] != "a" || lines[1] != "b" {
+		t.Fatalf("calls = %d, lines = %v", calls, lines)
+	}
+}
@@ -0,0 +1,231 @@
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultNetwork     = "udp"
+	defaultAddr        = "127.0.0.1:514"
+	defaultDialTimeout = 5 * time.Second
+)
+
+// Facility is a syslog facility, see RFC 5424 section 6.2.1.
+type Facility int
+
+const (
+	Kern   Facility = 0
+	User   Facility = 1
+	Daemon Facility = 3
+	Local0 Facility = 16
+	Local1 Facility = 17
+	Local2 Facility = 18
+	Local3 Facility = 19
+	Local4 Facility = 20
+	Local5 Facility = 21
+	Local6 Facility = 22
+	Local7 Facility = 23
+)
+
+// severity of the levels of the JSON encoder, see RFC 5424 section 6.2.1.
+var severities = map[string]int{
+	"debug":  7,
+	"info":   6,
+	"warn":   4,
+	"error":  3,
+	"dpanic": 2,
+	"panic":  2,
+	"fatal":  0,
+}
+
+const defaultSeverity = 6
+
+type Options struct {
+	// Network is udp, tcp, unix or unixgram. unix tries unixgram first like
+	// log/syslog, as /dev/log is a datagram socket on most systems.
+	Network     string
+	Addr        string
+	Facility    Facility
+	AppName     string
+	Hostname    string
+	DialTimeout time.Duration
+}
+
+type Option func(*Options)
+
+// Writer writes each line as an RFC 5424 message. The severity is read from
+// the "level" of a JSON line, default informational. Over stream sockets,
+// tcp or unix, messages are framed by octet counting, see RFC 6587.
+type Writer struct {
+	opts Options
+	pid  string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	stream bool
+}
+
+func NewWriter(opts ...Option) *Writer {
+	options := Options{
+		Network:     defaultNetwork,
+		Addr:        defaultAddr,
+		Facility:    Local0,
+		AppName:     filepath.Base(os.Args[0]),
+		DialTimeout: defaultDialTimeout,
+	}
+	options.Hostname, _ = os.Hostname()
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &Writer{
+		opts: options,
+		pid:  strconv.Itoa(os.Getpid()),
+	}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	msg := w.format(bytes.TrimRight(p, "\n"), time.Now())
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// retry once with a new connection, the collector may have restarted
+	var err error
+	for i := 0; i < 2; i++ {
+		if w.conn == nil {
+			if err = w.dial(); err != nil {
+				return 0, err
+			}
+		}
+		b := msg
+		if w.stream {
+			b = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		}
+		if _, err = w.conn.Write(b); err == nil {
+			return len(p), nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+func (w *Writer) dial() error {
+	network := w.opts.Network
+	if network == "unix" {
+		conn, err := net.DialTimeout("unixgram", w.opts.Addr, w.opts.DialTimeout)
+		if err == nil {
+			w.conn, w.stream = conn, false
+			return nil
+		}
+	}
+	conn, err := net.DialTimeout(network, w.opts.Addr, w.opts.DialTimeout)
+	if err != nil {
+		return err
+	}
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		w.stream = true
+	default:
+		w.stream = false
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *Writer) format(p []byte, t time.Time) []byte {
+	pri := int(w.opts.Facility)*8 + severity(p)
+	var b bytes.Buffer
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s - - ",
+		pri, t.Format(time.RFC3339Nano), nilValue(w.opts.Hostname), nilValue(w.opts.AppName), w.pid)
+	b.Write(p)
+	return b.Bytes()
+}
+
+func severity(p []byte) int {
+	i := bytes.Index(p, []byte(`"level":"`))
+	if i < 0 {
+		return defaultSeverity
+	}
+	rest := p[i+len(`"level":"`):]
+	j := bytes.IndexByte(rest, '"')
+	if j < 0 {
+		return defaultSeverity
+	}
+	if s, ok := severities[string(rest[:j])]; ok {
+		return s
+	}
+	return defaultSeverity
+}
+
+func nilValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Sync does nothing, the messages are not buffered.
+func (w *Writer) Sync() error {
+	return nil
+}
+
+// Flush is Sync.
+func (w *Writer) Flush() error {
+	return w.Sync()
+}
+
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func Network(s string) Option {
+	return func(o *Options) {
+		o.Network = s
+	}
+}
+
+func Addr(s string) Option {
+	return func(o *Options) {
+		o.Addr = s
+	}
+}
+
+func WithFacility(f Facility) Option {
+	return func(o *Options) {
+		o.Facility = f
+	}
+}
+
+func AppName(s string) Option {
+	return func(o *Options) {
+		o.AppName = s
+	}
+}
+
+func Hostname(s string) Option {
+	return func(o *Options) {
+		o.Hostname = s
+	}
+}
+
+func DialTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.DialTimeout = d
+	}
+}
@@ -0,0 +1,92 @@
+package syslog
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w := NewWriter(Addr(conn.LocalAddr().String()), AppName("app"), Hostname("host"))
+	defer w.Close()
+	if _, err = w.Write([]byte(`{"level":"error","msg":"boom"}` + "\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	// local0 * 8 + error
+	if !strings.HasPrefix(msg, "<131>1 ") || !strings.Contains(msg, " host app ") ||
+		!strings.HasSuffix(msg, ` - - {"level":"error","msg":"boom"}`) {
+		t.Fatalf("unexpected message: %q", msg)
+	}
+}
+
+func TestWriteUnix(t *testing.T) {
+	dir := t.TempDir()
+
+	// a datagram socket like /dev/log
+	gram := dir + "/gram.sock"
+	conn, err := net.ListenPacket("unixgram", gram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w := NewWriter(Network("unix"), Addr(gram), AppName("app"), Hostname("host"))
+	defer w.Close()
+	if _, err = w.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := string(buf[:n]); !strings.HasPrefix(msg, "<134>1 ") || !strings.HasSuffix(msg, " - - hello") {
+		t.Fatalf("unexpected message: %q", msg)
+	}
+
+	// a stream socket is framed by octet counting
+	stream := dir + "/stream.sock"
+	l, err := net.Listen("unix", stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	w2 := NewWriter(Network("unix"), Addr(stream), AppName("app"), Hostname("host"))
+	defer w2.Close()
+	if _, err = w2.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	c, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err = c.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	size, rest, _ := strings.Cut(msg, " ")
+	if size != strconv.Itoa(len(rest)) || !strings.HasPrefix(rest, "<134>1 ") {
+		t.Fatalf("unexpected message: %q", msg)
+	}
+}
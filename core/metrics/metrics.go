@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the request collectors shared by the http and rpc
+// transports. All of them are labeled by the called operation, e.g.
+// route and method for http, service and method for rpc, and its status.
+type Metrics struct {
+	opts Options
+
+	httpServerRequests *prometheus.CounterVec
+	httpServerDuration *prometheus.HistogramVec
+	httpClientRequests *prometheus.CounterVec
+	httpClientDuration *prometheus.HistogramVec
+	rpcServerRequests  *prometheus.CounterVec
+	rpcServerDuration  *prometheus.HistogramVec
+	rpcClientRequests  *prometheus.CounterVec
+	rpcClientDuration  *prometheus.HistogramVec
+	panics             *prometheus.CounterVec
+}
+
+// New creates and registers the collectors. Collectors that are already
+// registered, e.g. by another Metrics on the same registry, are reused.
+func New(opts ...Option) (*Metrics, error) {
+	options := newOptions(opts...)
+	m := &Metrics{opts: options}
+
+	httpLabels := []string{"route", "method", "status"}
+	rpcLabels := []string{"service", "method", "status"}
+
+	var err error
+	if m.httpServerRequests, err = m.counter("http_server", httpLabels); err != nil {
+		return nil, err
+	}
+	if m.httpServerDuration, err = m.histogram("http_server", httpLabels); err != nil {
+		return nil, err
+	}
+	if m.httpClientRequests, err = m.counter("http_client", httpLabels); err != nil {
+		return nil, err
+	}
+	if m.httpClientDuration, err = m.histogram("http_client", httpLabels); err != nil {
+		return nil, err
+	}
+	if m.rpcServerRequests, err = m.counter("rpc_server", rpcLabels); err != nil {
+		return nil, err
+	}
+	if m.rpcServerDuration, err = m.histogram("rpc_server", rpcLabels); err != nil {
+		return nil, err
+	}
+	if m.rpcClientRequests, err = m.counter("rpc_client", rpcLabels); err != nil {
+		return nil, err
+	}
+	if m.rpcClientDuration, err = m.histogram("rpc_client", rpcLabels); err != nil {
+		return nil, err
+	}
+	if m.panics, err = m.panicCounter(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Metrics) counter(subsystem string, labels []string) (*prometheus.CounterVec, error) {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: m.opts.Namespace,
+		Subsystem: subsystem,
+		Name:      "requests_total",
+		Help:      "The total number of requests.",
+	}, labels)
+	if err := m.opts.Registerer.Register(c); err != nil {
+		are := prometheus.AlreadyRegisteredError{}
+		if errors.As(err, &are) {
+			return are.ExistingCollector.(*prometheus.CounterVec), nil
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (m *Metrics) histogram(subsystem string, labels []string) (*prometheus.HistogramVec, error) {
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: m.opts.Namespace,
+		Subsystem: subsystem,
+		Name:      "request_duration_seconds",
+		Help:      "The request latencies in seconds.",
+		Buckets:   m.opts.Buckets,
+	}, labels)
+	if err := m.opts.Registerer.Register(h); err != nil {
+		are := prometheus.AlreadyRegisteredError{}
+		if errors.As(err, &are) {
+			return are.ExistingCollector.(*prometheus.HistogramVec), nil
+		}
+		return nil, err
+	}
+	return h, nil
+}
+
+func (m *Metrics) panicCounter() (*prometheus.CounterVec, error) {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: m.opts.Namespace,
+		Name:      "panics_total",
+		Help:      "The total number of panics recovered by the servers.",
+	}, []string{"transport"})
+	if err := m.opts.Registerer.Register(c); err != nil {
+		are := prometheus.AlreadyRegisteredError{}
+		if errors.As(err, &are) {
+			return are.ExistingCollector.(*prometheus.CounterVec), nil
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// Handler serves the gathered metrics in the prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.opts.Gatherer, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTPServer records an inbound http request.
+func (m *Metrics) ObserveHTTPServer(route, method string, status int, d time.Duration) {
+	s := strconv.Itoa(status)
+	m.httpServerRequests.WithLabelValues(route, method, s).Inc()
+	m.httpServerDuration.WithLabelValues(route, method, s).Observe(d.Seconds())
+}
+
+// ObserveHTTPClient records an outbound http request. status is 0 when
+// no response was received.
+func (m *Metrics) ObserveHTTPClient(route, method string, status int, d time.Duration) {
+	s := strconv.Itoa(status)
+	m.httpClientRequests.WithLabelValues(route, method, s).Inc()
+	m.httpClientDuration.WithLabelValues(route, method, s).Observe(d.Seconds())
+}
+
+// ObserveRPCServer records an inbound rpc call.
+func (m *Metrics) ObserveRPCServer(service, method string, err error, d time.Duration) {
+	s := rpcStatus(err)
+	m.rpcServerRequests.WithLabelValues(service, method, s).Inc()
+	m.rpcServerDuration.WithLabelValues(service, method, s).Observe(d.Seconds())
+}
+
+// ObserveRPCClient records an outbound rpc call.
+func (m *Metrics) ObserveRPCClient(service, method string, err error, d time.Duration) {
+	s := rpcStatus(err)
+	m.rpcClientRequests.WithLabelValues(service, method, s).Inc()
+	m.rpcClientDuration.WithLabelValues(service, method, s).Observe(d.Seconds())
+}
+
+func rpcStatus(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// ObservePanic records a panic recovered by a server of transport, e.g.
+// http or rpc.
+func (m *Metrics) ObservePanic(transport string) {
+	m.panics.WithLabelValues(transport).Inc()
+}
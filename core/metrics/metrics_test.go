@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	r := prometheus.NewRegistry()
+	m, err := New(Registry(r), Namespace("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.ObserveHTTPServer("/hello/:name", http.MethodGet, 200, time.Millisecond)
+	m.ObserveHTTPServer("/hello/:name", http.MethodGet, 200, time.Millisecond)
+	m.ObserveRPCServer("Greeter", "SayHello", errors.New("boom"), time.Millisecond)
+
+	if v := testutil.ToFloat64(m.httpServerRequests.WithLabelValues("/hello/:name", "GET", "200")); v != 2 {
+		t.Fatalf("http server requests: got %v, want 2", v)
+	}
+	if v := testutil.ToFloat64(m.rpcServerRequests.WithLabelValues("Greeter", "SayHello", "error")); v != 1 {
+		t.Fatalf("rpc server requests: got %v, want 1", v)
+	}
+
+	// collectors are shared between instances on the same registry
+	m2, err := New(Registry(r), Namespace("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2.ObserveHTTPServer("/hello/:name", http.MethodGet, 200, time.Millisecond)
+	if v := testutil.ToFloat64(m.httpServerRequests.WithLabelValues("/hello/:name", "GET", "200")); v != 3 {
+		t.Fatalf("http server requests: got %v, want 3", v)
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(w.Body.String(), `test_http_server_requests_total{method="GET",route="/hello/:name",status="200"} 3`) {
+		t.Fatalf("unexpected exposition:\n%s", w.Body.String())
+	}
+}
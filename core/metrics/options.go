@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type Options struct {
+	// Registerer registers the collectors. default prometheus.DefaultRegisterer
+	Registerer prometheus.Registerer
+	// Gatherer is served by Handler. default prometheus.DefaultGatherer
+	Gatherer prometheus.Gatherer
+	// Namespace prefixes every metric name.
+	Namespace string
+	// Buckets of the latency histograms in seconds. default prometheus.DefBuckets
+	Buckets []float64
+}
+
+type Option func(*Options)
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Registerer: prometheus.DefaultRegisterer,
+		Gatherer:   prometheus.DefaultGatherer,
+		Buckets:    prometheus.DefBuckets,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
+
+// Registry uses r both as Registerer and Gatherer, useful in tests.
+func Registry(r *prometheus.Registry) Option {
+	return func(o *Options) {
+		o.Registerer = r
+		o.Gatherer = r
+	}
+}
+
+func Registerer(r prometheus.Registerer) Option {
+	return func(o *Options) {
+		o.Registerer = r
+	}
+}
+
+func Gatherer(g prometheus.Gatherer) Option {
+	return func(o *Options) {
+		o.Gatherer = g
+	}
+}
+
+func Namespace(s string) Option {
+	return func(o *Options) {
+		o.Namespace = s
+	}
+}
+
+func Buckets(b []float64) Option {
+	return func(o *Options) {
+		o.Buckets = b
+	}
+}
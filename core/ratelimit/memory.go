@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepEvery is the number of requests between two sweeps of the idle
+// keys of a MemoryStore.
+const sweepEvery = 1024
+
+// MemoryStore is a Store in memory, so the limits apply per instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	windows map[string]*window
+	n       int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// idle is when the bucket is full again
+	idle time.Time
+}
+
+type window struct {
+	start time.Time
+	cur   int
+	prev  int
+	idle  time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		windows: make(map[string]*window),
+	}
+}
+
+func (s *MemoryStore) TakeToken(_ context.Context, key string, rate float64, burst int, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	r := Result{Limit: burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		r.Allowed = true
+	} else if rate > 0 {
+		r.RetryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	} else {
+		r.RetryAfter = math.MaxInt64
+	}
+	r.Remaining = int(b.tokens)
+	if rate > 0 {
+		b.idle = now.Add(time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second)))
+	} else {
+		b.idle = time.Time{}
+	}
+	return r, nil
+}
+
+func (s *MemoryStore) Hit(_ context.Context, key string, limit int, d time.Duration, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	w, ok := s.windows[key]
+	if !ok {
+		w = &window{}
+		s.windows[key] = w
+	}
+	r := slide(w, limit, d, now)
+	w.idle = w.start.Add(2 * d)
+	return r, nil
+}
+
+// slide counts a request in w, the sliding window algorithm of the stores.
+func slide(w *window, limit int, d time.Duration, now time.Time) Result {
+	start := now.Truncate(d)
+	if !w.start.Equal(start) {
+		if start.Sub(w.start) == d {
+			w.prev = w.cur
+		} else {
+			w.prev = 0
+		}
+		w.cur = 0
+		w.start = start
+	}
+
+	// the previous window counts for the part still in the sliding window
+	f := float64(now.Sub(start)) / float64(d)
+	count := float64(w.prev)*(1-f) + float64(w.cur)
+
+	r := Result{Limit: limit}
+	if count+1 <= float64(limit) {
+		w.cur++
+		r.Allowed = true
+		r.Remaining = int(float64(limit) - count - 1)
+		return r
+	}
+	if w.cur+1 > limit || w.prev == 0 {
+		r.RetryAfter = start.Add(d).Sub(now)
+	} else {
+		// when the previous window has slid enough out
+		at := 1 - float64(limit-w.cur-1)/float64(w.prev)
+		r.RetryAfter = start.Add(time.Duration(at * float64(d))).Sub(now)
+	}
+	return r
+}
+
+// sweep drops the keys back to their initial state, s.mu must be held.
+func (s *MemoryStore) sweep(now time.Time) {
+	s.n++
+	if s.n < sweepEvery {
+		return
+	}
+	s.n = 0
+	for k, b := range s.buckets {
+		if !b.idle.IsZero() && now.After(b.idle) {
+			delete(s.buckets, k)
+		}
+	}
+	for k, w := range s.windows {
+		if now.After(w.idle) {
+			delete(s.windows, k)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+
+	for i := 0; i < 3; i++ {
+		r, _ := s.TakeToken(ctx, "k", 1, 3, now)
+		if !r.Allowed || r.Remaining != 2-i {
+			t.Fatalf("take %d: %+v", i, r)
+		}
+	}
+	r, _ := s.TakeToken(ctx, "k", 1, 3, now)
+	if r.Allowed || r.RetryAfter != time.Second {
+		t.Fatalf("over burst: %+v", r)
+	}
+	// other keys have their own bucket
+	if r, _ = s.TakeToken(ctx, "other", 1, 3, now); !r.Allowed {
+		t.Fatalf("other key: %+v", r)
+	}
+	// refilled at rate
+	if r, _ = s.TakeToken(ctx, "k", 1, 3, now.Add(time.Second)); !r.Allowed {
+		t.Fatalf("after refill: %+v", r)
+	}
+}
+
+func TestMemoryStoreSlidingWindow(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	start := time.Unix(1000, 0).Truncate(10 * time.Second)
+
+	for i := 0; i < 4; i++ {
+		if r, _ := s.Hit(ctx, "k", 4, 10*time.Second, start.Add(time.Second)); !r.Allowed {
+			t.Fatalf("hit %d: %+v", i, r)
+		}
+	}
+	r, _ := s.Hit(ctx, "k", 4, 10*time.Second, start.Add(2*time.Second))
+	if r.Allowed || r.RetryAfter != 8*time.Second {
+		t.Fatalf("over limit: %+v", r)
+	}
+	// half of the previous window still counts: 4*0.5 = 2
+	next := start.Add(15 * time.Second)
+	for i := 0; i < 2; i++ {
+		if r, _ = s.Hit(ctx, "k", 4, 10*time.Second, next); !r.Allowed {
+			t.Fatalf("next window hit %d: %+v", i, r)
+		}
+	}
+	if r, _ = s.Hit(ctx, "k", 4, 10*time.Second, next); r.Allowed {
+		t.Fatalf("next window over limit: %+v", r)
+	}
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Result is the outcome of a request counted by a Limiter.
+type Result struct {
+	Allowed bool
+	// Limit is the burst of a token bucket or the limit of a window.
+	Limit int
+	// Remaining is the number of requests still allowed now.
+	Remaining int
+	// RetryAfter is when the next request may be allowed, if not allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter limits the requests per key.
+type Limiter interface {
+	// Allow counts a request of key and reports whether it is allowed.
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// Store keeps the state of the limits per key. A shared store, e.g. Redis,
+// limits the requests across all the instances of a service. Limiters
+// sharing a store must use distinct keys.
+type Store interface {
+	// TakeToken takes a token from the bucket of key, which holds up to
+	// burst tokens and is refilled with rate tokens per second.
+	TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (Result, error)
+	// Hit counts a request of key in a sliding window, allowing limit
+	// requests per window.
+	Hit(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (Result, error)
+}
+
+// TokenBucket returns a Limiter that allows bursts of burst requests and
+// rate requests per second on average.
+func TokenBucket(s Store, rate float64, burst int) Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{store: s, rate: rate, burst: burst}
+}
+
+type tokenBucket struct {
+	store Store
+	rate  float64
+	burst int
+}
+
+func (l *tokenBucket) Allow(ctx context.Context, key string) (Result, error) {
+	return l.store.TakeToken(ctx, key, l.rate, l.burst, time.Now())
+}
+
+// SlidingWindow returns a Limiter that allows limit requests in any window
+// of the given duration. The window is approximated from the counts of the
+// current and the previous fixed windows.
+func SlidingWindow(s Store, limit int, window time.Duration) Limiter {
+	return &slidingWindow{store: s, limit: limit, window: window}
+}
+
+type slidingWindow struct {
+	store  Store
+	limit  int
+	window time.Duration
+}
+
+func (l *slidingWindow) Allow(ctx context.Context, key string) (Result, error) {
+	return l.store.Hit(ctx, key, l.limit, l.window, time.Now())
+}
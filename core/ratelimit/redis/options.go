@@ -0,0 +1,26 @@
+package redis
+
+type Options struct {
+	// Prefix of the keys in Redis. default "ratelimit:"
+	Prefix string
+}
+
+type Option func(*Options)
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Prefix: "ratelimit:",
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
+
+func Prefix(s string) Option {
+	return func(o *Options) {
+		o.Prefix = s
+	}
+}
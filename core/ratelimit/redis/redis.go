@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/zmicro-team/zmicro/core/ratelimit"
+)
+
+var _ ratelimit.Store = (*Store)(nil)
+
+// tokenBucket refills and takes a token of the bucket KEYS[1].
+// ARGV: rate per second, burst, now in ms.
+// returns allowed, remaining, retry after in ms.
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - last) * rate / 1000)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+elseif rate > 0 then
+  retry = math.ceil((1 - tokens) * 1000 / rate)
+else
+  retry = -1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'last', now)
+if rate > 0 then
+  redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) * 1000 / rate) + 1000)
+end
+return {allowed, math.floor(tokens), retry}
+`)
+
+// slidingWindow counts a request in the window KEYS[1].
+// ARGV: limit, window in ms, now in ms.
+// returns allowed, remaining, retry after in ms.
+var slidingWindow = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local start = now - now % window
+
+local state = redis.call('HMGET', KEYS[1], 'start', 'cur', 'prev')
+local cur = tonumber(state[2]) or 0
+local prev = tonumber(state[3]) or 0
+local last = tonumber(state[1])
+if last ~= start then
+  if last ~= nil and start - last == window then
+    prev = cur
+  else
+    prev = 0
+  end
+  cur = 0
+end
+
+local f = (now - start) / window
+local count = prev * (1 - f) + cur
+if count + 1 <= limit then
+  cur = cur + 1
+  redis.call('HSET', KEYS[1], 'start', start, 'cur', cur, 'prev', prev)
+  redis.call('PEXPIRE', KEYS[1], 2 * window)
+  return {1, math.floor(limit - count - 1), 0}
+end
+
+redis.call('HSET', KEYS[1], 'start', start, 'cur', cur, 'prev', prev)
+redis.call('PEXPIRE', KEYS[1], 2 * window)
+local retry
+if cur + 1 > limit or prev == 0 then
+  retry = start + window - now
+else
+  retry = start + math.ceil((1 - (limit - cur - 1) / prev) * window) - now
+end
+return {0, 0, retry}
+`)
+
+// Store is a ratelimit.Store in Redis, so the limits apply across all the
+// instances of a service. The state of the limits is computed by scripts
+// so that concurrent requests are counted atomically.
+type Store struct {
+	opts   Options
+	client redis.Scripter
+}
+
+// NewStore creates a Store that runs its scripts with client, e.g. a
+// *redis.Client or a *redis.ClusterClient.
+func NewStore(client redis.Scripter, opts ...Option) *Store {
+	return &Store{
+		opts:   newOptions(opts...),
+		client: client,
+	}
+}
+
+func (s *Store) TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (ratelimit.Result, error) {
+	v, err := tokenBucket.Run(ctx, s.client, []string{s.opts.Prefix + "tb:" + key},
+		rate, burst, now.UnixMilli()).Int64Slice()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	return result(v, burst), nil
+}
+
+func (s *Store) Hit(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (ratelimit.Result, error) {
+	v, err := slidingWindow.Run(ctx, s.client, []string{s.opts.Prefix + "sw:" + key},
+		limit, window.Milliseconds(), now.UnixMilli()).Int64Slice()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	return result(v, limit), nil
+}
+
+func result(v []int64, limit int) ratelimit.Result {
+	r := ratelimit.Result{
+		Allowed:    v[0] == 1,
+		Limit:      limit,
+		Remaining:  int(v[1]),
+		RetryAfter: time.Duration(v[2]) * time.Millisecond,
+	}
+	// the bucket is never refilled
+	if v[2] < 0 {
+		r.RetryAfter = math.MaxInt64
+	}
+	return r
+}
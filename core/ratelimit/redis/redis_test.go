@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newStore(t *testing.T) *Store {
+	mr := miniredis.RunT(t)
+	c := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = c.Close() })
+	return NewStore(c)
+}
+
+func TestStoreTokenBucket(t *testing.T) {
+	s := newStore(t)
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+
+	for i := 0; i < 3; i++ {
+		r, err := s.TakeToken(ctx, "k", 1, 3, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !r.Allowed || r.Remaining != 2-i {
+			t.Fatalf("take %d: %+v", i, r)
+		}
+	}
+	r, _ := s.TakeToken(ctx, "k", 1, 3, now)
+	if r.Allowed || r.RetryAfter != time.Second {
+		t.Fatalf("over burst: %+v", r)
+	}
+	if r, _ = s.TakeToken(ctx, "k", 1, 3, now.Add(time.Second)); !r.Allowed {
+		t.Fatalf("after refill: %+v", r)
+	}
+}
+
+func TestStoreSlidingWindow(t *testing.T) {
+	s := newStore(t)
+	ctx := context.Background()
+	start := time.UnixMilli(1000_000)
+
+	for i := 0; i < 4; i++ {
+		r, err := s.Hit(ctx, "k", 4, 10*time.Second, start.Add(time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !r.Allowed {
+			t.Fatalf("hit %d: %+v", i, r)
+		}
+	}
+	r, _ := s.Hit(ctx, "k", 4, 10*time.Second, start.Add(2*time.Second))
+	if r.Allowed || r.RetryAfter != 8*time.Second {
+		t.Fatalf("over limit: %+v", r)
+	}
+	next := start.Add(15 * time.Second)
+	for i := 0; i < 2; i++ {
+		if r, _ = s.Hit(ctx, "k", 4, 10*time.Second, next); !r.Allowed {
+			t.Fatalf("next window hit %d: %+v", i, r)
+		}
+	}
+	if r, _ = s.Hit(ctx, "k", 4, 10*time.Second, next); r.Allowed {
+		t.Fatalf("next window over limit: %+v", r)
+	}
+}
@@ -54,3 +54,13 @@ func CarrierInterceptor(carrier Carrier) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RenderError renders err with the Carrier of the request if any,
+// otherwise with Error.
+func RenderError(c *gin.Context, err error) {
+	if carrier, ok := c.Request.Context().Value(ctxCarrierKey{}).(Carrier); ok {
+		carrier.Error(c, err)
+		return
+	}
+	Error(c, err)
+}
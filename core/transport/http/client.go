@@ -6,9 +6,11 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/zmicro-team/zmicro/core/encoding"
+	"github.com/zmicro-team/zmicro/core/metrics"
 	"golang.org/x/oauth2"
 )
 
@@ -30,6 +32,8 @@ type Client struct {
 	validate func(any) error
 	// call option
 	callOptions []CallOption
+	// metrics records outbound requests when not nil
+	metrics *metrics.Metrics
 }
 
 type ClientOption func(*Client)
@@ -59,6 +63,12 @@ func WithValidate(f func(any) error) ClientOption {
 	}
 }
 
+func WithMetrics(m *metrics.Metrics) ClientOption {
+	return func(c *Client) {
+		c.metrics = m
+	}
+}
+
 func WithCallOption(co ...CallOption) ClientOption {
 	return func(c *Client) {
 		c.callOptions = append(c.callOptions, co...)
@@ -151,7 +161,17 @@ func (c *Client) invokeInner(ctx context.Context, method, path string, in, out a
 			r.Header.Add(k, v)
 		}
 	}
+	// the server may stop working on the request when the caller gives up
+	setRequestTimeout(ctx, r.Header)
+	start := time.Now()
 	resp, err := r.Execute(method, c.cc.BaseURL+path)
+	if c.metrics != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode()
+		}
+		c.metrics.ObserveHTTPClient(settings.Path, method, status, time.Since(start))
+	}
 	if err != nil {
 		return err
 	}
@@ -2,106 +2,112 @@ package logging
 
 import (
 	"bytes"
-	"context"
+	"encoding/json"
 	"io"
 	"mime"
 	"net"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
 	"os"
+	"path"
 	"runtime/debug"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gin-gonic/gin"
-	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"github.com/zmicro-team/zmicro/core/log"
 )
 
-var (
-	responseBodyLimit = 2048 // <=0 mean not limit
-	slowThreshold     = time.Millisecond * 500
-)
+const maskedValue = "******"
 
+// rspWriter keeps the first limit+1 bytes of the body, enough to know if it
+// is larger than limit.
 type rspWriter struct {
 	gin.ResponseWriter
-	body *bytes.Buffer
+	body  *bytes.Buffer
+	limit int
 }
 
-func (w rspWriter) Write(b []byte) (int, error) {
-	n, err := w.body.Write(b)
-	if err != nil {
-		return n, err
+func (w rspWriter) keep(b []byte) {
+	if w.limit > 0 {
+		if n := w.limit + 1 - w.body.Len(); n < len(b) {
+			b = b[:max(n, 0)]
+		}
 	}
+	w.body.Write(b)
+}
 
+func (w rspWriter) Write(b []byte) (int, error) {
+	w.keep(b)
 	return w.ResponseWriter.Write(b)
 }
 
 func (w rspWriter) WriteString(s string) (int, error) {
-	n, err := w.body.WriteString(s)
-	if err != nil {
-		return n, err
-	}
-
+	w.keep([]byte(s))
 	return w.ResponseWriter.WriteString(s)
 }
 
-func copyHeader(header http.Header) http.Header {
-	h := http.Header{}
-	for k, v := range header {
-		h[k] = v
-	}
-	return h
+// readCloser reads the buffered start of a body and then the rest of it.
+type readCloser struct {
+	io.Reader
+	io.Closer
 }
 
-func skipRequestBody(c *gin.Context) bool {
-	// skip request body rule
-	// skip MultipartForm file
-	v := c.Request.Header.Get("Content-Type")
-	d, params, err := mime.ParseMediaType(v)
-	if err != nil || !(d == "multipart/form-data" || d == "multipart/mixed") {
-		return false
-	}
-	_, ok := params["boundary"]
-	return ok
+type logger struct {
+	opts          Options
+	redactHeaders map[string]struct{}
+	redactFields  map[string]struct{}
 }
 
-func skipResponseBody(c *gin.Context) bool {
-	// TODO: add skip response body rule
-	return false
-}
+// Log returns a middleware that logs the requests with their headers and
+// bodies, see Options for what is skipped and redacted.
+func Log(opts ...Option) gin.HandlerFunc {
+	l := &logger{
+		opts:          newOptions(opts...),
+		redactHeaders: make(map[string]struct{}),
+		redactFields:  make(map[string]struct{}),
+	}
+	for _, h := range l.opts.RedactHeaders {
+		l.redactHeaders[http.CanonicalHeaderKey(h)] = struct{}{}
+	}
+	for _, f := range l.opts.RedactFields {
+		l.redactFields[fieldKey(f)] = struct{}{}
+	}
 
-func Log() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if l.skipPath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
 		var start = time.Now()
 
-		respBodyBuf := &bytes.Buffer{}
 		reqBody := "skip request body"
-
-		if !skipRequestBody(c) {
+		if !l.skipBody(c.Request.Header.Get("Content-Type")) {
+			// read no more than needed to know if the body is larger than
+			// the limit, the handler reads the rest
+			var r io.Reader = c.Request.Body
+			if l.opts.RequestBodyLimit > 0 {
+				r = io.LimitReader(r, int64(l.opts.RequestBodyLimit)+1)
+			}
 			buf := &bytes.Buffer{}
-			c.Request.Body = io.NopCloser(io.TeeReader(c.Request.Body, buf))
-			if _, err := io.ReadAll(c.Request.Body); err != nil {
+			if _, err := io.Copy(buf, r); err != nil {
 				c.Abort()
 				return
 			}
-
-			reqBody = buf.String()
-			c.Request.Body = io.NopCloser(buf)
+			reqBody = l.body(buf.Bytes(), c.Request.Header.Get("Content-Type"), l.opts.RequestBodyLimit, "skip larger request body")
+			c.Request.Body = readCloser{io.MultiReader(buf, c.Request.Body), c.Request.Body}
 		}
-		c.Writer = &rspWriter{c.Writer, respBodyBuf}
+
+		w := &rspWriter{c.Writer, &bytes.Buffer{}, l.opts.ResponseBodyLimit}
+		c.Writer = w
 
 		defer func() {
-			if checkPrefix(c.Request.RequestURI, "/swagger") {
-				return
-			}
-			fields := make([]zap.Field, 0, 14)
-			traceId := getTraceId(c.Request.Context())
-			if traceId != "" {
-				fields = append(fields, zap.String("trace_id", traceId))
-			}
+			fields := make([]zap.Field, 0, 12)
 			fields = append(fields, zap.String("type", "http"))
 			fields = append(fields, zap.Int("status", c.Writer.Status()))
 			fields = append(fields, zap.String("method", c.Request.Method))
@@ -113,55 +119,174 @@ func Log() gin.HandlerFunc {
 			duration := time.Since(start)
 			fields = append(fields, zap.Duration("duration", duration))
 			fields = append(fields, zap.Any("req", map[string]interface{}{
-				"header": copyHeader(c.Request.Header),
+				"header": l.header(c.Request.Header),
 				"body":   reqBody,
 			}))
 
 			respBody := "skip response body"
-			if !skipResponseBody(c) {
-				if responseBodyLimit > 0 && respBodyBuf.Len() >= responseBodyLimit {
-					respBody = "skip larger response body"
-				} else {
-					respBody = respBodyBuf.String()
-				}
+			if !l.skipBody(c.Writer.Header().Get("Content-Type")) {
+				respBody = l.body(w.body.Bytes(), c.Writer.Header().Get("Content-Type"), l.opts.ResponseBodyLimit, "skip larger response body")
 			}
 			fields = append(fields, zap.Any("rsp", map[string]interface{}{
-				"header": copyHeader(c.Writer.Header()),
+				"header": l.header(c.Writer.Header()),
 				"body":   respBody,
 			}))
 
-			// slow log
-			if duration > slowThreshold {
-				log.Warn("slow", fields...)
+			// trace_id, span_id and request_id
+			lg := log.FromContext(c.Request.Context())
+			if duration > l.opts.SlowThreshold {
+				logAt(lg, l.opts.SlowLevel, "slow", fields...)
 			}
-			log.Info("access", fields...)
+			lg.Info("access", fields...)
 		}()
 
 		c.Next()
 	}
 }
 
-func getTraceId(ctx context.Context) string {
-	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
-		return sc.TraceID().String()
+func logAt(l *log.Logger, level log.Level, msg string, fields ...zap.Field) {
+	switch level {
+	case log.DebugLevel:
+		l.Debug(msg, fields...)
+	case log.InfoLevel:
+		l.Info(msg, fields...)
+	case log.WarnLevel:
+		l.Warn(msg, fields...)
+	default:
+		l.Error(msg, fields...)
 	}
-	return ""
 }
 
-func checkPrefix(s string, prefixes ...string) bool {
-	for _, p := range prefixes {
-		if strings.HasPrefix(s, p) {
+func (l *logger) skipPath(p string) bool {
+	for _, pattern := range l.opts.SkipPaths {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(p, prefix) {
+			return true
+		}
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *logger) skipBody(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	d, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range l.opts.SkipContentTypes {
+		if strings.HasPrefix(d, strings.ToLower(t)) {
 			return true
 		}
 	}
 	return false
 }
 
+func (l *logger) header(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		if _, ok := l.redactHeaders[http.CanonicalHeaderKey(k)]; ok {
+			out[k] = []string{maskedValue}
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// body returns b with its JSON or form fields redacted, or skip if it is
+// larger than limit.
+func (l *logger) body(b []byte, contentType string, limit int, skip string) string {
+	if limit > 0 && len(b) > limit {
+		return skip
+	}
+	if len(l.redactFields) > 0 && isForm(contentType) {
+		return l.redactForm(string(b))
+	}
+	if len(l.redactFields) == 0 || !json.Valid(b) {
+		return string(b)
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return string(b)
+	}
+	if !l.redact(v) {
+		return string(b)
+	}
+	redacted, err := json.Marshal(v)
+	if err != nil {
+		return string(b)
+	}
+	return string(redacted)
+}
+
+// fieldKey is the name of a field without case and separators, so that
+// access_token, accessToken and Access-Token are the same field.
+func fieldKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', '.', ' ':
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
+
+func (l *logger) isRedacted(name string) bool {
+	_, ok := l.redactFields[fieldKey(name)]
+	return ok
+}
+
+func isForm(contentType string) bool {
+	d, _, err := mime.ParseMediaType(contentType)
+	return err == nil && d == "application/x-www-form-urlencoded"
+}
+
+// redactForm masks the values of the redacted fields of a form, keeping
+// the order and encoding of the others.
+func (l *logger) redactForm(s string) string {
+	pairs := strings.Split(s, "&")
+	for i, p := range pairs {
+		k, _, _ := strings.Cut(p, "=")
+		if name, err := url.QueryUnescape(k); err == nil && l.isRedacted(name) {
+			pairs[i] = k + "=" + maskedValue
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// redact masks the redacted fields of v, and reports if any is found.
+func (l *logger) redact(v any) bool {
+	found := false
+	switch vv := v.(type) {
+	case map[string]any:
+		for k, e := range vv {
+			if l.isRedacted(k) {
+				vv[k] = maskedValue
+				found = true
+				continue
+			}
+			found = l.redact(e) || found
+		}
+	case []any:
+		for _, e := range vv {
+			found = l.redact(e) || found
+		}
+	}
+	return found
+}
+
 // Recovery returns a gin.HandlerFunc (middleware)
 // that recovers from any panics and logs requests using uber-go/zap.
 // All errors are logged using zap.Error().
 // stack means whether output the stack info.
 // The stack info is easy to find where the error occurs but the stack info is too large.
+//
+// Deprecated: http.Server installs recovery.Recovery, which renders the
+// error and reports the panic.
 func Recovery(stack bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
@@ -0,0 +1,134 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zmicro-team/zmicro/core/log"
+)
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	old := log.Default()
+	log.ResetDefault(log.New(&buf, log.InfoLevel))
+	defer log.ResetDefault(old)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Log(SkipPaths("/healthz"), SlowThreshold(time.Hour), ResponseBodyLimit(10)))
+	r.POST("/login", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"token": "t0k3n", "user": "bob"})
+	})
+	r.GET("/healthz", func(c *gin.Context) {})
+	r.GET("/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", []byte("png-bytes"))
+	})
+
+	do := func(method, target, body string) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	do(http.MethodPost, "/login", `{"user":"bob","password":"p@ss"}`)
+	do(http.MethodGet, "/healthz", "")
+	do(http.MethodGet, "/image", "")
+
+	out := buf.String()
+	for _, leaked := range []string{"p@ss", "Bearer secret", "t0k3n", "png-bytes", "/healthz"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("%q is logged: %s", leaked, out)
+		}
+	}
+	for _, want := range []string{
+		`\"password\":\"******\"`,
+		`"skip larger response body"`,
+		`"skip response body"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("%s does not contain %s", out, want)
+		}
+	}
+}
+
+// countingReader counts the bytes read from it.
+type countingReader struct {
+	r *strings.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestLogLargeSlowRequest(t *testing.T) {
+	var buf bytes.Buffer
+	old := log.Default()
+	log.ResetDefault(log.New(&buf, log.InfoLevel))
+	defer log.ResetDefault(old)
+
+	body := &countingReader{r: strings.NewReader(strings.Repeat("a", 100))}
+	var read, got int
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Log(RequestBodyLimit(10), SlowThreshold(0)))
+	r.POST("/upload", func(c *gin.Context) {
+		read = body.n
+		b, _ := io.ReadAll(c.Request.Body)
+		got = len(b)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if read != 11 {
+		t.Errorf("read %d bytes before the handler, want 11", read)
+	}
+	if got != 100 {
+		t.Errorf("handler got %d bytes, want 100", got)
+	}
+	out := buf.String()
+	for _, want := range []string{`"skip larger request body"`, `"msg":"slow"`, `"msg":"access"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("%s does not contain %s", out, want)
+		}
+	}
+}
+
+func TestLogRedactOAuth(t *testing.T) {
+	var buf bytes.Buffer
+	old := log.Default()
+	log.ResetDefault(log.New(&buf, log.InfoLevel))
+	defer log.ResetDefault(old)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Log(SlowThreshold(time.Hour)))
+	r.POST("/oauth/token", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"access_token": "at-123", "refresh_token": "rt-456", "token_type": "bearer"})
+	})
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token",
+		strings.NewReader("grant_type=client_credentials&client_id=web&client_secret=s3cr3t"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := buf.String()
+	for _, leaked := range []string{"at-123", "rt-456", "s3cr3t"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("%q is logged: %s", leaked, out)
+		}
+	}
+	for _, want := range []string{"client_id=web", "client_secret=******", "bearer"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("%s does not contain %s", out, want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"time"
+
+	"github.com/zmicro-team/zmicro/core/log"
+)
+
+type Options struct {
+	// SkipPaths are not logged. The patterns are matched with path.Match,
+	// and a trailing * also matches the nested paths, e.g. /swagger*.
+	SkipPaths []string
+	// RequestBodyLimit and ResponseBodyLimit are the largest bodies
+	// logged, <=0 means no limit.
+	RequestBodyLimit  int
+	ResponseBodyLimit int
+	// RedactHeaders are the headers whose values are masked.
+	RedactHeaders []string
+	// RedactFields are the JSON body fields, at any depth, and the form
+	// fields whose values are masked. The names are matched regardless of
+	// case and separators, e.g. access_token also masks accessToken.
+	RedactFields []string
+	// SkipContentTypes are the media type prefixes of the bodies that are
+	// not logged, e.g. image/ or text/event-stream.
+	SkipContentTypes []string
+	// SlowThreshold is the duration from which requests are also logged as
+	// slow at SlowLevel, before their access line.
+	SlowThreshold time.Duration
+	SlowLevel     log.Level
+}
+
+type Option func(*Options)
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		SkipPaths:         []string{"/swagger*"},
+		RequestBodyLimit:  2048,
+		ResponseBodyLimit: 2048,
+		RedactHeaders: []string{
+			"Authorization",
+			"Proxy-Authorization",
+			"Cookie",
+			"Set-Cookie",
+			"X-Api-Key",
+		},
+		RedactFields: []string{
+			"password",
+			"secret",
+			"token",
+			"access_token",
+			"refresh_token",
+			"id_token",
+			"client_secret",
+			"api_key",
+		},
+		SkipContentTypes: []string{
+			"multipart/",
+			"image/",
+			"audio/",
+			"video/",
+			"font/",
+			"application/octet-stream",
+			"application/pdf",
+			"application/zip",
+			"application/grpc",
+			"text/event-stream",
+		},
+		SlowThreshold: 500 * time.Millisecond,
+		SlowLevel:     log.WarnLevel,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
+
+// SkipPaths adds paths that are not logged.
+func SkipPaths(p ...string) Option {
+	return func(o *Options) {
+		o.SkipPaths = append(o.SkipPaths, p...)
+	}
+}
+
+func RequestBodyLimit(n int) Option {
+	return func(o *Options) {
+		o.RequestBodyLimit = n
+	}
+}
+
+func ResponseBodyLimit(n int) Option {
+	return func(o *Options) {
+		o.ResponseBodyLimit = n
+	}
+}
+
+// RedactHeaders adds headers whose values are masked.
+func RedactHeaders(h ...string) Option {
+	return func(o *Options) {
+		o.RedactHeaders = append(o.RedactHeaders, h...)
+	}
+}
+
+// RedactFields adds JSON fields whose values are masked.
+func RedactFields(f ...string) Option {
+	return func(o *Options) {
+		o.RedactFields = append(o.RedactFields, f...)
+	}
+}
+
+// SkipContentTypes adds media type prefixes of the bodies that are not
+// logged.
+func SkipContentTypes(t ...string) Option {
+	return func(o *Options) {
+		o.SkipContentTypes = append(o.SkipContentTypes, t...)
+	}
+}
+
+func SlowThreshold(d time.Duration) Option {
+	return func(o *Options) {
+		o.SlowThreshold = d
+	}
+}
+
+func SlowLevel(l log.Level) Option {
+	return func(o *Options) {
+		o.SlowLevel = l
+	}
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zmicro-team/zmicro/core/metrics"
+)
+
+// Server records the count and latency of every request by route, method
+// and status. Unmatched requests share one route label to bound cardinality.
+func Server(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.ObserveHTTPServer(route, c.Request.Method, c.Writer.Status(), time.Since(start))
+	}
+}
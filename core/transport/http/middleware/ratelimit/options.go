@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/zmicro-team/zmicro/core/ratelimit"
+	"github.com/zmicro-team/zmicro/core/transport/http"
+)
+
+type Options struct {
+	// Limiter default 100 requests per second with bursts of 100, in
+	// memory.
+	Limiter ratelimit.Limiter
+	// Key default ClientIp
+	Key KeyFunc
+	// Render writes the rejection, default with the Carrier of the request.
+	Render func(c *gin.Context, err error)
+	// FailOpen allows the requests when the Limiter fails, e.g. when
+	// Redis is down. default true
+	FailOpen bool
+}
+
+type Option func(*Options)
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Key:      ClientIp,
+		Render:   http.RenderError,
+		FailOpen: true,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Limiter == nil {
+		options.Limiter = ratelimit.TokenBucket(ratelimit.NewMemoryStore(), 100, 100)
+	}
+	return options
+}
+
+func Limiter(l ratelimit.Limiter) Option {
+	return func(o *Options) {
+		o.Limiter = l
+	}
+}
+
+func Key(f KeyFunc) Option {
+	return func(o *Options) {
+		o.Key = f
+	}
+}
+
+func Render(f func(c *gin.Context, err error)) Option {
+	return func(o *Options) {
+		o.Render = f
+	}
+}
+
+func FailOpen(b bool) Option {
+	return func(o *Options) {
+		o.FailOpen = b
+	}
+}
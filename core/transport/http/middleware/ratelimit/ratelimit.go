@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	zerrors "github.com/zmicro-team/zmicro/core/errors"
+	"github.com/zmicro-team/zmicro/core/log"
+	"github.com/zmicro-team/zmicro/core/transport"
+)
+
+const (
+	HeaderLimit     = "X-RateLimit-Limit"
+	HeaderRemaining = "X-RateLimit-Remaining"
+	HeaderRetry     = "Retry-After"
+)
+
+// KeyFunc returns the key a request is limited by. Requests with an empty
+// key are not limited.
+type KeyFunc func(c *gin.Context) string
+
+// ClientIp keys the requests by the ip of the client.
+func ClientIp(c *gin.Context) string {
+	if tr, ok := transport.FromTransporter(c.Request.Context()); ok {
+		return tr.ClientIp()
+	}
+	return c.ClientIP()
+}
+
+// Route keys the requests by their method and route, e.g. GET /users/:id.
+func Route(c *gin.Context) string {
+	return c.Request.Method + " " + c.FullPath()
+}
+
+// Header keys the requests by the value of the header name, e.g. an api
+// key.
+func Header(name string) KeyFunc {
+	return func(c *gin.Context) string {
+		return c.GetHeader(name)
+	}
+}
+
+// Keys keys the requests by all of f, e.g. Keys(Route, ClientIp) limits
+// each client per route. The key is empty if one of them is.
+func Keys(f ...KeyFunc) KeyFunc {
+	return func(c *gin.Context) string {
+		keys := make([]string, 0, len(f))
+		for _, fn := range f {
+			k := fn(c)
+			if k == "" {
+				return ""
+			}
+			keys = append(keys, k)
+		}
+		return strings.Join(keys, "|")
+	}
+}
+
+// RateLimit returns a middleware that limits the requests per key. The
+// rejected requests get errors.ErrTooManyRequests with a Retry-After
+// header.
+func RateLimit(opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts...)
+	return func(c *gin.Context) {
+		key := o.Key(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		r, err := o.Limiter.Allow(c.Request.Context(), key)
+		if err != nil {
+			log.FromContext(c.Request.Context()).Warnf("ratelimit: %v", err)
+			if o.FailOpen {
+				c.Next()
+				return
+			}
+			o.Render(c, zerrors.ErrServiceUnavailable(""))
+			c.Abort()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set(HeaderLimit, strconv.Itoa(r.Limit))
+		h.Set(HeaderRemaining, strconv.Itoa(r.Remaining))
+		if !r.Allowed {
+			h.Set(HeaderRetry, strconv.FormatInt(retryAfter(r.RetryAfter), 10))
+			o.Render(c, zerrors.ErrTooManyRequests(""))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// retryAfter returns d in whole seconds, rounded up.
+func retryAfter(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zmicro-team/zmicro/core/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RateLimit(
+		Limiter(ratelimit.TokenBucket(ratelimit.NewMemoryStore(), 0.001, 2)),
+		Key(Header("X-Api-Key")),
+	))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if key != "" {
+			req.Header.Set("X-Api-Key", key)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := do("a"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: %d", i, w.Code)
+		}
+	}
+	w := do("a")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get(HeaderRetry) == "" {
+		t.Fatalf("got %d, Retry-After %q", w.Code, w.Header().Get(HeaderRetry))
+	}
+	if w = do("b"); w.Code != http.StatusOK {
+		t.Fatalf("other key: %d", w.Code)
+	}
+	// requests without key are not limited
+	for i := 0; i < 3; i++ {
+		if w = do(""); w.Code != http.StatusOK {
+			t.Fatalf("no key: %d", w.Code)
+		}
+	}
+}
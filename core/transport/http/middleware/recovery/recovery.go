@@ -0,0 +1,143 @@
+package recovery
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	zerrors "github.com/zmicro-team/zmicro/core/errors"
+	"github.com/zmicro-team/zmicro/core/log"
+	"github.com/zmicro-team/zmicro/core/metrics"
+	"github.com/zmicro-team/zmicro/core/transport"
+)
+
+type Options struct {
+	// Render writes the error response, default the errors.Error as JSON.
+	Render   func(c *gin.Context, err error)
+	Reporter transport.PanicReporter
+	Metrics  *metrics.Metrics
+	// Stack logs the stack of the panic. default true
+	Stack bool
+}
+
+type Option func(*Options)
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Render: func(c *gin.Context, err error) {
+			c.JSON(http.StatusInternalServerError, err)
+		},
+		Stack: true,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
+
+func Render(f func(c *gin.Context, err error)) Option {
+	return func(o *Options) {
+		o.Render = f
+	}
+}
+
+func Reporter(r transport.PanicReporter) Option {
+	return func(o *Options) {
+		o.Reporter = r
+	}
+}
+
+func Metrics(m *metrics.Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = m
+	}
+}
+
+func Stack(b bool) Option {
+	return func(o *Options) {
+		o.Stack = b
+	}
+}
+
+// Recovery returns a middleware that recovers from the panics of the next
+// handlers. The panic is logged, recorded on the span and the metrics, and
+// reported, then errors.ErrInternalServer is rendered. A broken connection
+// is only logged, as nothing can be written to it.
+func Recovery(opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts...)
+	return func(c *gin.Context) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			ctx := c.Request.Context()
+			l := log.FromContext(ctx)
+
+			httpRequest, _ := httputil.DumpRequest(c.Request, false)
+			if brokenPipe(p) {
+				l.Error(c.Request.URL.Path,
+					zap.Any("error", p),
+					zap.ByteString("request", httpRequest),
+				)
+				if err, ok := p.(error); ok {
+					_ = c.Error(err)
+				}
+				c.Abort()
+				return
+			}
+
+			stack := debug.Stack()
+			fields := make([]zap.Field, 0, 3)
+			fields = append(fields,
+				zap.Any("error", p),
+				zap.ByteString("request", httpRequest),
+			)
+			if o.Stack {
+				fields = append(fields, zap.ByteString("stack", stack))
+			}
+			l.Error("recovery from panic", fields...)
+
+			transport.RecordPanic(ctx, p, stack)
+			if o.Metrics != nil {
+				o.Metrics.ObservePanic("http")
+			}
+			if o.Reporter != nil {
+				o.Reporter(ctx, p, stack)
+			}
+
+			o.Render(c, zerrors.ErrInternalServer(""))
+			c.Abort()
+		}()
+		c.Next()
+	}
+}
+
+// brokenPipe reports whether p is a broken connection, which is not really
+// a condition that warrants a panic stack trace.
+func brokenPipe(p any) bool {
+	err, ok := p.(error)
+	if !ok {
+		return false
+	}
+	var ne *net.OpError
+	if !errors.As(err, &ne) {
+		return false
+	}
+	var se *os.SyscallError
+	if !errors.As(ne.Err, &se) {
+		return false
+	}
+	msg := strings.ToLower(se.Error())
+	return strings.Contains(msg, "broken pipe") ||
+		strings.Contains(msg, "connection reset by peer")
+}
@@ -0,0 +1,54 @@
+package recovery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/zmicro-team/zmicro/core/metrics"
+)
+
+func TestRecovery(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := metrics.New(metrics.Registry(reg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reported any
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Recovery(
+		Metrics(m),
+		Reporter(func(_ context.Context, p any, stack []byte) {
+			if len(stack) == 0 {
+				t.Error("empty stack")
+			}
+			reported = p
+		}),
+	))
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"code":500`) {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	if reported != "boom" {
+		t.Fatalf("reported %v", reported)
+	}
+	want := `
+# HELP panics_total The total number of panics recovered by the servers.
+# TYPE panics_total counter
+panics_total{transport="http"} 1
+`
+	if err = testutil.GatherAndCompare(reg, strings.NewReader(want), "panics_total"); err != nil {
+		t.Fatal(err)
+	}
+}
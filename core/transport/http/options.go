@@ -1,19 +1,62 @@
 package http
 
-import "github.com/gin-gonic/gin"
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zmicro-team/zmicro/core/metrics"
+	"github.com/zmicro-team/zmicro/core/transport"
+	"github.com/zmicro-team/zmicro/core/transport/http/middleware/logging"
+)
 
 type Options struct {
-	Name           string
-	Addr           string
+	Name string
+	Addr string
+	// Listener is served instead of listening on Addr, e.g. for socket
+	// activation or tests.
+	Listener       net.Listener
 	InitHttpServer InitHttpServerFunc
 	Mode           string
 	Tracing        bool
+	// Metrics records requests when not nil.
+	Metrics *metrics.Metrics
+	// MetricsPath serves the Metrics, e.g. /metrics. empty means not served
+	MetricsPath string
+	// AccessLog configures the access log middleware.
+	AccessLog []logging.Option
+	// PanicReporter is called with the panics of the handlers.
+	PanicReporter transport.PanicReporter
+	// TLSConfig serves https when not nil, see util/tls.Reloader for
+	// certificates reloaded from files.
+	TLSConfig *tls.Config
+
+	// timeouts of net/http.Server, zero means no timeout
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// MaxHeaderBytes zero means net/http.DefaultMaxHeaderBytes
+	MaxHeaderBytes int
+	// MaxConns caps the concurrent connections, further ones wait to be
+	// accepted. zero means no limit
+	MaxConns int
+	// H2C serves HTTP/2 without tls, e.g. for internal traffic. It is
+	// ignored with TLSConfig, which negotiates HTTP/2 itself.
+	H2C bool
 }
 
 type Option func(*Options)
 
 func newOptions(opts ...Option) Options {
-	options := Options{}
+	options := Options{
+		// slow clients cannot hold connections open by sending headers
+		// byte by byte, nor keep idle connections forever
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
 
 	for _, o := range opts {
 		o(&options)
@@ -34,6 +77,12 @@ func Addr(s string) Option {
 	}
 }
 
+func Listener(l net.Listener) Option {
+	return func(o *Options) {
+		o.Listener = l
+	}
+}
+
 type InitHttpServerFunc func(r *gin.Engine) error
 
 func InitHttpServer(f InitHttpServerFunc) Option {
@@ -53,3 +102,75 @@ func Tracing(b bool) Option {
 		o.Tracing = b
 	}
 }
+
+func Metrics(m *metrics.Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = m
+	}
+}
+
+func MetricsPath(s string) Option {
+	return func(o *Options) {
+		o.MetricsPath = s
+	}
+}
+
+func AccessLog(opts ...logging.Option) Option {
+	return func(o *Options) {
+		o.AccessLog = append(o.AccessLog, opts...)
+	}
+}
+
+func PanicReporter(r transport.PanicReporter) Option {
+	return func(o *Options) {
+		o.PanicReporter = r
+	}
+}
+
+func TLSConfig(c *tls.Config) Option {
+	return func(o *Options) {
+		o.TLSConfig = c
+	}
+}
+
+func ReadTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.ReadTimeout = d
+	}
+}
+
+func ReadHeaderTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.ReadHeaderTimeout = d
+	}
+}
+
+func WriteTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.WriteTimeout = d
+	}
+}
+
+func IdleTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.IdleTimeout = d
+	}
+}
+
+func MaxHeaderBytes(n int) Option {
+	return func(o *Options) {
+		o.MaxHeaderBytes = n
+	}
+}
+
+func MaxConns(n int) Option {
+	return func(o *Options) {
+		o.MaxConns = n
+	}
+}
+
+func H2C(b bool) Option {
+	return func(o *Options) {
+		o.H2C = b
+	}
+}
@@ -8,8 +8,13 @@ import (
 	"time"
 
 	"github.com/zmicro-team/zmicro/core/transport/http/middleware/logging"
+	"github.com/zmicro-team/zmicro/core/transport/http/middleware/metrics"
+	"github.com/zmicro-team/zmicro/core/transport/http/middleware/recovery"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/net/netutil"
 
 	"github.com/zmicro-team/zmicro/core/log"
 	"github.com/zmicro-team/zmicro/core/transport/http/middleware/tracing"
@@ -19,6 +24,8 @@ type Server struct {
 	opts Options
 	*gin.Engine
 	server *http.Server
+	addr   net.Addr
+	errc   chan error
 }
 
 func NewServer(opts ...Option) *Server {
@@ -26,6 +33,7 @@ func NewServer(opts ...Option) *Server {
 
 	srv := &Server{
 		opts: options,
+		errc: make(chan error, 1),
 	}
 
 	gin.SetMode(srv.opts.Mode)
@@ -42,13 +50,27 @@ func (s *Server) Init(opts ...Option) {
 }
 
 func (s *Server) Start() error {
+	// first, so that the panics of the other middlewares and of the
+	// metrics route are recovered too
+	s.Engine.Use(recovery.Recovery(
+		recovery.Render(RenderError),
+		recovery.Reporter(s.opts.PanicReporter),
+		recovery.Metrics(s.opts.Metrics),
+	))
 	s.Engine.Use(TransportInterceptor())
 
 	if s.opts.Tracing {
 		s.Engine.Use(tracing.Trace(s.opts.Name))
 	}
 
-	s.Engine.Use(logging.Log())
+	s.Engine.Use(logging.Log(s.opts.AccessLog...))
+
+	if s.opts.Metrics != nil {
+		s.Engine.Use(metrics.Server(s.opts.Metrics))
+		if s.opts.MetricsPath != "" {
+			s.Engine.GET(s.opts.MetricsPath, gin.WrapH(s.opts.Metrics.Handler()))
+		}
+	}
 
 	if s.opts.InitHttpServer != nil {
 		if err := s.opts.InitHttpServer(s.Engine); err != nil {
@@ -56,24 +78,66 @@ func (s *Server) Start() error {
 		}
 	}
 
-	l, err := net.Listen("tcp", s.opts.Addr)
-	if err != nil {
-		return err
+	s.server.ReadTimeout = s.opts.ReadTimeout
+	s.server.ReadHeaderTimeout = s.opts.ReadHeaderTimeout
+	s.server.WriteTimeout = s.opts.WriteTimeout
+	s.server.IdleTimeout = s.opts.IdleTimeout
+	s.server.MaxHeaderBytes = s.opts.MaxHeaderBytes
+	if s.opts.H2C && s.opts.TLSConfig == nil {
+		s.server.Handler = h2c.NewHandler(s.Engine, &http2.Server{IdleTimeout: s.opts.IdleTimeout})
+	}
+
+	l := s.opts.Listener
+	if l == nil {
+		var err error
+		if l, err = net.Listen("tcp", s.opts.Addr); err != nil {
+			return err
+		}
+	}
+	s.addr = l.Addr()
+	if s.opts.MaxConns > 0 {
+		l = netutil.LimitListener(l, s.opts.MaxConns)
 	}
-	a := l.Addr().String()
-	log.Infof("Server [GIN] listening on %s", a)
+	// the certificates are in the config
+	s.server.TLSConfig = s.opts.TLSConfig
+	log.Infof("Server [GIN] listening on %s", s.addr)
 	go func() {
-		if err := s.server.Serve(l); err != nil {
-			if errors.Is(err, http.ErrServerClosed) {
-				log.Fatal(err.Error())
-			}
+		defer close(s.errc)
+		var err error
+		if s.opts.TLSConfig != nil {
+			err = s.server.ServeTLS(l, "", "")
+		} else {
+			err = s.server.Serve(l)
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Server [GIN] serve error: %v", err)
+			s.errc <- err
 		}
 	}()
 	return nil
 }
 
+// Addr returns the address the server is bound to, nil before Start.
+func (s *Server) Addr() net.Addr {
+	return s.addr
+}
+
+// Err returns a channel that receives the error of the server when it
+// stops serving for another reason than Shutdown. It is closed when the
+// server stops serving.
+func (s *Server) Err() <-chan error {
+	return s.errc
+}
+
 func (s *Server) Stop() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	return s.Shutdown(ctx)
+}
+
+// Shutdown gracefully shuts down the server, waiting for in-flight
+// requests to finish until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	log.Info("Server [GIN] stopping")
 	return s.server.Shutdown(ctx)
 }
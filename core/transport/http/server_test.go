@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http2"
+)
+
+func startServer(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+	s := NewServer(append([]Option{Addr("127.0.0.1:0"), Mode(gin.TestMode)}, opts...)...)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Stop() })
+	return s
+}
+
+func TestServerLimits(t *testing.T) {
+	s := startServer(t,
+		ReadTimeout(time.Second),
+		ReadHeaderTimeout(2*time.Second),
+		WriteTimeout(3*time.Second),
+		IdleTimeout(4*time.Second),
+		MaxHeaderBytes(4096),
+	)
+	got := []time.Duration{s.server.ReadTimeout, s.server.ReadHeaderTimeout, s.server.WriteTimeout, s.server.IdleTimeout}
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("timeout %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+	if s.server.MaxHeaderBytes != 4096 {
+		t.Errorf("MaxHeaderBytes: got %d, want 4096", s.server.MaxHeaderBytes)
+	}
+}
+
+func TestServerMaxConns(t *testing.T) {
+	s := startServer(t, MaxConns(1), InitHttpServer(func(r *gin.Engine) error {
+		r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+		return nil
+	}))
+	url := "http://" + s.Addr().String() + "/"
+
+	// an idle connection takes the only slot
+	idle, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{
+		Timeout:   200 * time.Millisecond,
+		Transport: &http.Transport{DisableKeepAlives: true},
+	}
+	if _, err = client.Get(url); err == nil {
+		t.Fatal("a second connection is served")
+	}
+
+	_ = idle.Close()
+	client.Timeout = 5 * time.Second
+	rsp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rsp.Body.Close()
+}
+
+func TestServerH2C(t *testing.T) {
+	s := startServer(t, H2C(true), InitHttpServer(func(r *gin.Engine) error {
+		r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, c.Request.Proto) })
+		return nil
+	}))
+
+	// prior knowledge, no upgrade from HTTP/1.1
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	rsp, err := client.Get("http://" + s.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	b, _ := io.ReadAll(rsp.Body)
+	if string(b) != "HTTP/2.0" {
+		t.Fatalf("got %s, want HTTP/2.0", b)
+	}
+}
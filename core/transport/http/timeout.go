@@ -0,0 +1,149 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	zerrors "github.com/zmicro-team/zmicro/core/errors"
+)
+
+const (
+	// RequestTimeoutHeader carries the time left to the caller, as a
+	// duration like 1.5s or as milliseconds.
+	RequestTimeoutHeader = "X-Request-Timeout"
+	// GrpcTimeoutHeader carries the time left to the caller like grpc,
+	// e.g. 100m for 100 milliseconds.
+	GrpcTimeoutHeader = "Grpc-Timeout"
+)
+
+// Timeout returns a middleware that sets the deadline of the request
+// context to d from now, or to the time left to the caller given by the
+// X-Request-Timeout or grpc-timeout header when it is sooner. d zero only
+// honors the caller. The header never extends or removes d.
+//
+// The handlers are not interrupted, they should return when the context is
+// done. If the deadline passes before they write the response,
+// errors.ErrGatewayTimeout is rendered with the Carrier instead. The
+// deadline goes on with the context: the http Client sends the time left
+// in X-Request-Timeout, and rpcx sends it itself in the __ServerTimeout
+// metadata, which the rpc server applies to the context of the service.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := d
+		if t, ok := RequestTimeout(c.Request.Header); ok && (timeout <= 0 || t < timeout) {
+			timeout = t
+		}
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		w := &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.expired || (!c.Writer.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+			RenderError(c, zerrors.ErrGatewayTimeout(""))
+		}
+	}
+}
+
+// RequestTimeout returns the time left to the caller given by the
+// X-Request-Timeout or grpc-timeout header of h. Values that are not
+// positive are ignored.
+func RequestTimeout(h http.Header) (time.Duration, bool) {
+	if v := h.Get(RequestTimeoutHeader); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d, true
+		}
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil && ms > 0 {
+			return time.Duration(ms) * time.Millisecond, true
+		}
+	}
+	if v := h.Get(GrpcTimeoutHeader); len(v) > 1 {
+		n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+		if err != nil || n <= 0 {
+			return 0, false
+		}
+		unit, ok := grpcTimeoutUnits[v[len(v)-1]]
+		if !ok {
+			return 0, false
+		}
+		return time.Duration(n) * unit, true
+	}
+	return 0, false
+}
+
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// setRequestTimeout passes the time left before the deadline of ctx on to
+// the server in h.
+func setRequestTimeout(ctx context.Context, h http.Header) {
+	deadline, ok := ctx.Deadline()
+	if !ok || h.Get(RequestTimeoutHeader) != "" {
+		return
+	}
+	if left := time.Until(deadline).Truncate(time.Millisecond); left > 0 {
+		h.Set(RequestTimeoutHeader, left.String())
+	}
+}
+
+// timeoutWriter drops the response of the handlers once the deadline has
+// passed, unless they started writing it before.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx     context.Context
+	expired bool
+}
+
+func (w *timeoutWriter) drop() bool {
+	if !w.expired && !w.ResponseWriter.Written() && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.expired = true
+	}
+	return w.expired
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	if w.drop() {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	if w.drop() {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	if w.drop() {
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *timeoutWriter) Flush() {
+	if w.drop() {
+		return
+	}
+	w.ResponseWriter.Flush()
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/slow", Timeout(20*time.Millisecond), func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		c.String(http.StatusOK, "late")
+	})
+	r.GET("/fast", Timeout(time.Second), func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/deadline", Timeout(time.Minute), func(c *gin.Context) {
+		d, _ := c.Request.Context().Deadline()
+		if time.Until(d) > time.Second {
+			c.String(http.StatusOK, "caller ignored")
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/bounded", Timeout(time.Second), func(c *gin.Context) {
+		d, ok := c.Request.Context().Deadline()
+		if !ok || time.Until(d) > time.Second || time.Until(d) < 500*time.Millisecond {
+			c.String(http.StatusOK, "route timeout lost")
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	tests := []struct {
+		path   string
+		header map[string]string
+		code   int
+	}{
+		{path: "/slow", code: http.StatusGatewayTimeout},
+		{path: "/fast", code: http.StatusOK},
+		{path: "/deadline", header: map[string]string{RequestTimeoutHeader: "500ms"}, code: http.StatusOK},
+		{path: "/deadline", header: map[string]string{GrpcTimeoutHeader: "500m"}, code: http.StatusOK},
+		{path: "/bounded", header: map[string]string{RequestTimeoutHeader: "0"}, code: http.StatusOK},
+		{path: "/bounded", header: map[string]string{RequestTimeoutHeader: "-1s"}, code: http.StatusOK},
+		{path: "/bounded", header: map[string]string{RequestTimeoutHeader: "1h"}, code: http.StatusOK},
+		{path: "/bounded", header: map[string]string{GrpcTimeoutHeader: "0S"}, code: http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		for k, v := range tt.header {
+			req.Header.Set(k, v)
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code || (tt.code == http.StatusOK && w.Body.String() != "ok") {
+			t.Errorf("%s %v: got %d %q, want %d", tt.path, tt.header, w.Code, w.Body.String(), tt.code)
+		}
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		key, value string
+		want       time.Duration
+		ok         bool
+	}{
+		{RequestTimeoutHeader, "1.5s", 1500 * time.Millisecond, true},
+		{RequestTimeoutHeader, "250", 250 * time.Millisecond, true},
+		{GrpcTimeoutHeader, "2S", 2 * time.Second, true},
+		{GrpcTimeoutHeader, "100u", 100 * time.Microsecond, true},
+		{GrpcTimeoutHeader, "10x", 0, false},
+		{RequestTimeoutHeader, "soon", 0, false},
+		{RequestTimeoutHeader, "0", 0, false},
+		{RequestTimeoutHeader, "-1s", 0, false},
+		{GrpcTimeoutHeader, "0m", 0, false},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		h.Set(tt.key, tt.value)
+		got, ok := RequestTimeout(h)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: %s = %v %v, want %v %v", tt.key, tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PanicReporter is called with the panics recovered by the servers, e.g.
+// to report them to an error tracker. stack is nil when it is unknown.
+type PanicReporter func(ctx context.Context, p any, stack []byte)
+
+// RecordPanic records the panic p on the span of ctx, if any.
+func RecordPanic(ctx context.Context, p any, stack []byte) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	err, ok := p.(error)
+	if !ok {
+		err = fmt.Errorf("%v", p)
+	}
+	span.RecordError(err, trace.WithAttributes(
+		attribute.Bool("exception.escaped", true),
+		attribute.String("exception.stacktrace", string(stack)),
+	))
+	span.SetStatus(codes.Error, "panic")
+}
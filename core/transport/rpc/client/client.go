@@ -34,6 +34,7 @@ func NewClient(opts ...Option) (*Client, error) {
 		}
 		opt := client.DefaultOption
 		opt.SerializeType = protocol.ProtoBuffer
+		opt.TLSConfig = c.opts.TLSConfig
 		c.xClient = client.NewXClient(
 			c.opts.ServiceName,
 			client.Failtry,
@@ -50,14 +51,20 @@ func NewClient(opts ...Option) (*Client, error) {
 
 		opt := client.DefaultOption
 		opt.SerializeType = protocol.ProtoBuffer
+		opt.TLSConfig = c.opts.TLSConfig
 		c.xClient = client.NewXClient(c.opts.ServiceName, client.Failtry, client.RoundRobin, d, opt)
 	}
 
+	pc := client.NewPluginContainer()
 	if c.opts.Tracing {
 		tracer := otel.Tracer("rpcx")
 		p := otelClient.NewOpenTelemetryPlugin(tracer, nil)
-		pc := client.NewPluginContainer()
 		pc.Add(p)
+	}
+	if c.opts.Metrics != nil {
+		pc.Add(NewMetricsPlugin(c.opts.Metrics))
+	}
+	if len(pc.All()) > 0 {
 		c.xClient.SetPlugins(pc)
 	}
 
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	rpcxServer "github.com/smallnest/rpcx/server"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/zmicro-team/zmicro/core/transport/rpc/server"
+)
+
+type deadlineService struct{}
+
+// Left replies the time left before the deadline of the call.
+func (deadlineService) Left(ctx context.Context, _ *wrapperspb.StringValue, reply *durationpb.Duration) error {
+	if d, ok := ctx.Deadline(); ok {
+		left := durationpb.New(time.Until(d))
+		reply.Seconds, reply.Nanos = left.Seconds, left.Nanos
+	}
+	return nil
+}
+
+func TestClientDeadline(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := server.NewServer(
+		server.Listener(l),
+		server.InitRpcServer(func(s *rpcxServer.Server) error {
+			return s.RegisterName("Deadline", new(deadlineService), "")
+		}),
+	)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	c, err := NewClient(WithServiceName("Deadline"), WithServiceAddr(l.Addr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.GetXClient().Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	reply := &durationpb.Duration{}
+	if err := c.GetXClient().Call(ctx, "Left", wrapperspb.String(""), reply); err != nil {
+		t.Fatal(err)
+	}
+	if left := reply.AsDuration(); left <= 0 || left > 500*time.Millisecond {
+		t.Fatalf("server got %v left, want the deadline of the caller", left)
+	}
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/smallnest/rpcx/client"
+	"github.com/smallnest/rpcx/share"
+
+	"github.com/zmicro-team/zmicro/core/metrics"
+)
+
+var (
+	_ client.PreCallPlugin  = (*MetricsPlugin)(nil)
+	_ client.PostCallPlugin = (*MetricsPlugin)(nil)
+)
+
+type ctxMetricsStartKey struct{}
+
+// MetricsPlugin records the count and latency of every call by service,
+// method and status.
+type MetricsPlugin struct {
+	m *metrics.Metrics
+}
+
+func NewMetricsPlugin(m *metrics.Metrics) *MetricsPlugin {
+	return &MetricsPlugin{m: m}
+}
+
+func (p *MetricsPlugin) PreCall(ctx context.Context, _, _ string, _ any) error {
+	if sc, ok := ctx.(*share.Context); ok {
+		sc.SetValue(ctxMetricsStartKey{}, time.Now())
+	}
+	return nil
+}
+
+func (p *MetricsPlugin) PostCall(ctx context.Context, servicePath, serviceMethod string, _, _ any, err error) error {
+	start, ok := ctx.Value(ctxMetricsStartKey{}).(time.Time)
+	if !ok {
+		return nil
+	}
+	p.m.ObserveRPCClient(servicePath, serviceMethod, err, time.Since(start))
+	return nil
+}
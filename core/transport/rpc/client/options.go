@@ -1,5 +1,11 @@
 package client
 
+import (
+	"crypto/tls"
+
+	"github.com/zmicro-team/zmicro/core/metrics"
+)
+
 type Options struct {
 	ServiceName string
 	ServiceAddr string
@@ -9,6 +15,9 @@ type Options struct {
 	EtcdAddr []string
 
 	Tracing bool
+	Metrics *metrics.Metrics
+	// TLSConfig dials the servers over tls when not nil.
+	TLSConfig *tls.Config
 }
 
 type Option func(*Options)
@@ -52,3 +61,15 @@ func Tracing(b bool) Option {
 		o.Tracing = b
 	}
 }
+
+func Metrics(m *metrics.Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = m
+	}
+}
+
+func TLSConfig(c *tls.Config) Option {
+	return func(o *Options) {
+		o.TLSConfig = c
+	}
+}
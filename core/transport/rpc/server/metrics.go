@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/smallnest/rpcx/protocol"
+	"github.com/smallnest/rpcx/server"
+	"github.com/smallnest/rpcx/share"
+
+	"github.com/zmicro-team/zmicro/core/metrics"
+)
+
+var (
+	_ server.PreHandleRequestPlugin  = (*MetricsPlugin)(nil)
+	_ server.PostWriteResponsePlugin = (*MetricsPlugin)(nil)
+)
+
+type ctxMetricsStartKey struct{}
+
+// MetricsPlugin records the count and latency of every call by service,
+// method and status.
+type MetricsPlugin struct {
+	m *metrics.Metrics
+}
+
+func NewMetricsPlugin(m *metrics.Metrics) *MetricsPlugin {
+	return &MetricsPlugin{m: m}
+}
+
+func (p *MetricsPlugin) PreHandleRequest(ctx context.Context, _ *protocol.Message) error {
+	if sc, ok := ctx.(*share.Context); ok {
+		sc.SetValue(ctxMetricsStartKey{}, time.Now())
+	}
+	return nil
+}
+
+func (p *MetricsPlugin) PostWriteResponse(ctx context.Context, req *protocol.Message, res *protocol.Message, err error) error {
+	start, ok := ctx.Value(ctxMetricsStartKey{}).(time.Time)
+	if !ok {
+		return nil
+	}
+	if err == nil && res != nil && res.MessageStatusType() == protocol.Error {
+		err = errors.New(res.Metadata[protocol.ServiceError])
+	}
+	p.m.ObserveRPCServer(req.ServicePath, req.ServiceMethod, err, time.Since(start))
+	return nil
+}
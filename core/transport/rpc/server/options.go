@@ -1,12 +1,23 @@
 package server
 
 import (
+	"crypto/tls"
+	"net"
+
 	"github.com/smallnest/rpcx/server"
+
+	"github.com/zmicro-team/zmicro/core/metrics"
+	"github.com/zmicro-team/zmicro/core/transport"
 )
 
 type Options struct {
-	Name          string
-	Addr          string
+	Name string
+	// Network is the listener network, "tcp" or "unix". default "tcp"
+	Network string
+	Addr    string
+	// Listener is served instead of listening on Addr, e.g. for socket
+	// activation or tests.
+	Listener      net.Listener
 	InitRpcServer InitRpcServerFunc
 
 	// registry
@@ -15,12 +26,20 @@ type Options struct {
 	EtcdAddr       []string
 
 	Tracing bool
+	Metrics *metrics.Metrics
+	// PanicReporter is called with the panics of the services.
+	PanicReporter transport.PanicReporter
+	// TLSConfig serves over tls when not nil, clients then need the
+	// client.TLSConfig option.
+	TLSConfig *tls.Config
 }
 
 type Option func(*Options)
 
 func newOptions(opts ...Option) Options {
-	options := Options{}
+	options := Options{
+		Network: "tcp",
+	}
 
 	for _, o := range opts {
 		o(&options)
@@ -35,12 +54,24 @@ func Name(s string) Option {
 	}
 }
 
+func Network(s string) Option {
+	return func(o *Options) {
+		o.Network = s
+	}
+}
+
 func Addr(s string) Option {
 	return func(o *Options) {
 		o.Addr = s
 	}
 }
 
+func Listener(l net.Listener) Option {
+	return func(o *Options) {
+		o.Listener = l
+	}
+}
+
 type InitRpcServerFunc func(s *server.Server) error
 
 func InitRpcServer(f InitRpcServerFunc) Option {
@@ -72,3 +103,21 @@ func Tracing(b bool) Option {
 		o.Tracing = b
 	}
 }
+
+func Metrics(m *metrics.Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = m
+	}
+}
+
+func PanicReporter(r transport.PanicReporter) Option {
+	return func(o *Options) {
+		o.PanicReporter = r
+	}
+}
+
+func TLSConfig(c *tls.Config) Option {
+	return func(o *Options) {
+		o.TLSConfig = c
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"net"
+
+	"github.com/smallnest/rpcx/server"
+
+	zerrors "github.com/zmicro-team/zmicro/core/errors"
+	"github.com/zmicro-team/zmicro/core/log"
+	"github.com/zmicro-team/zmicro/core/ratelimit"
+)
+
+var _ server.PreCallPlugin = (*RateLimitPlugin)(nil)
+
+// RateLimitKeyFunc returns the key a call is limited by. Calls with an
+// empty key are not limited.
+type RateLimitKeyFunc func(ctx context.Context, serviceName, methodName string) string
+
+// RemoteIp keys the calls by the ip of the client.
+func RemoteIp(ctx context.Context, _, _ string) string {
+	conn, ok := ctx.Value(server.RemoteConnContextKey).(net.Conn)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+	return host
+}
+
+// ServiceMethod keys the calls by their service and method.
+func ServiceMethod(_ context.Context, serviceName, methodName string) string {
+	return serviceName + "." + methodName
+}
+
+// RateLimitPlugin limits the calls per key like the http ratelimit
+// middleware. The rejected calls get errors.ErrTooManyRequests. When the
+// limiter fails the calls are allowed.
+type RateLimitPlugin struct {
+	limiter ratelimit.Limiter
+	key     RateLimitKeyFunc
+}
+
+// NewRateLimitPlugin creates a RateLimitPlugin, key default RemoteIp.
+func NewRateLimitPlugin(l ratelimit.Limiter, key RateLimitKeyFunc) *RateLimitPlugin {
+	if key == nil {
+		key = RemoteIp
+	}
+	return &RateLimitPlugin{limiter: l, key: key}
+}
+
+func (p *RateLimitPlugin) PreCall(ctx context.Context, serviceName, methodName string, args any) (any, error) {
+	key := p.key(ctx, serviceName, methodName)
+	if key == "" {
+		return args, nil
+	}
+	r, err := p.limiter.Allow(ctx, key)
+	if err != nil {
+		log.FromContext(ctx).Warnf("ratelimit: %v", err)
+		return args, nil
+	}
+	if !r.Allowed {
+		return args, zerrors.ErrTooManyRequests("")
+	}
+	return args, nil
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	zerrors "github.com/zmicro-team/zmicro/core/errors"
+	"github.com/zmicro-team/zmicro/core/ratelimit"
+)
+
+func TestRateLimitPlugin(t *testing.T) {
+	p := NewRateLimitPlugin(ratelimit.TokenBucket(ratelimit.NewMemoryStore(), 0.001, 1), ServiceMethod)
+	ctx := context.Background()
+
+	if _, err := p.PreCall(ctx, "Greeter", "SayHello", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.PreCall(ctx, "Greeter", "SayHello", nil); !zerrors.IsTooManyRequests(err) {
+		t.Fatalf("got %v, want too many requests", err)
+	}
+	// another method has its own limit
+	if _, err := p.PreCall(ctx, "Greeter", "SayBye", nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/smallnest/rpcx/protocol"
+	"github.com/smallnest/rpcx/server"
+	"github.com/smallnest/rpcx/share"
+	"go.uber.org/zap"
+
+	zerrors "github.com/zmicro-team/zmicro/core/errors"
+	"github.com/zmicro-team/zmicro/core/log"
+	"github.com/zmicro-team/zmicro/core/metrics"
+	"github.com/zmicro-team/zmicro/core/transport"
+)
+
+var (
+	_ server.PostCallPlugin         = (*RecoveryPlugin)(nil)
+	_ server.PreWriteResponsePlugin = (*RecoveryPlugin)(nil)
+)
+
+// rpcx recovers the panics of the services into errors with this prefix,
+// the panic value and the stack.
+const (
+	panicPrefix = "[service internal error]: "
+	stackSep    = ", stack: "
+)
+
+type ctxPanicKey struct{}
+
+// RecoveryPlugin handles the panics of the services like the http
+// recovery middleware: the panic is logged, recorded on the span and the
+// metrics, and reported, then errors.ErrInternalServer is returned to the
+// client instead of the panic and its stack.
+type RecoveryPlugin struct {
+	m        *metrics.Metrics
+	reporter transport.PanicReporter
+}
+
+func NewRecoveryPlugin(m *metrics.Metrics, r transport.PanicReporter) *RecoveryPlugin {
+	return &RecoveryPlugin{m: m, reporter: r}
+}
+
+func (p *RecoveryPlugin) PostCall(ctx context.Context, serviceName, methodName string, _, reply any, err error) (any, error) {
+	if err == nil || !strings.HasPrefix(err.Error(), panicPrefix) {
+		return reply, nil
+	}
+
+	msg, stack, _ := strings.Cut(strings.TrimPrefix(err.Error(), panicPrefix), stackSep)
+	log.FromContext(ctx).Error("recovery from panic",
+		zap.String("service", serviceName),
+		zap.String("method", methodName),
+		zap.String("error", msg),
+		zap.String("stack", stack),
+	)
+
+	panicErr := errors.New(msg)
+	transport.RecordPanic(ctx, panicErr, []byte(stack))
+	if p.m != nil {
+		p.m.ObservePanic("rpc")
+	}
+	if p.reporter != nil {
+		p.reporter(ctx, panicErr, []byte(stack))
+	}
+	if sc, ok := ctx.(*share.Context); ok {
+		sc.SetValue(ctxPanicKey{}, true)
+	}
+	return reply, nil
+}
+
+func (p *RecoveryPlugin) PreWriteResponse(ctx context.Context, _ *protocol.Message, res *protocol.Message, _ error) error {
+	if panicked, _ := ctx.Value(ctxPanicKey{}).(bool); panicked && res != nil {
+		res.Metadata[protocol.ServiceError] = zerrors.ErrInternalServer("").Error()
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/rpcx/protocol"
+	"github.com/smallnest/rpcx/share"
+)
+
+func TestRecoveryPlugin(t *testing.T) {
+	var reported any
+	p := NewRecoveryPlugin(nil, func(_ context.Context, v any, stack []byte) {
+		reported = v
+		if !strings.Contains(string(stack), "goroutine 1") {
+			t.Errorf("unexpected stack: %s", stack)
+		}
+	})
+	ctx := share.NewContext(context.Background())
+	err := errors.New(panicPrefix + "boom, method: Say, argv: &{}" + stackSep + "goroutine 1 [running]:")
+	if _, e := p.PostCall(ctx, "Greeter", "Say", nil, nil, err); e != nil {
+		t.Fatal(e)
+	}
+	if reported == nil || !strings.Contains(reported.(error).Error(), "boom") {
+		t.Fatalf("reported %v", reported)
+	}
+
+	res := protocol.NewMessage()
+	res.Metadata = map[string]string{protocol.ServiceError: err.Error()}
+	if e := p.PreWriteResponse(ctx, nil, res, nil); e != nil {
+		t.Fatal(e)
+	}
+	if got := res.Metadata[protocol.ServiceError]; strings.Contains(got, "goroutine") || !strings.Contains(got, `"code":500`) {
+		t.Fatalf("unexpected service error: %s", got)
+	}
+}
@@ -2,6 +2,8 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"net"
 	"strings"
 	"time"
@@ -17,14 +19,18 @@ import (
 )
 
 type Server struct {
-	server *server.Server
-	opts   Options
+	server   *server.Server
+	opts     Options
+	registry *etcdServerPlugin.EtcdV3RegisterPlugin
+	addr     net.Addr
+	errc     chan error
 }
 
 func NewServer(opts ...Option) *Server {
 	options := newOptions(opts...)
 	srv := &Server{
 		opts: options,
+		errc: make(chan error, 1),
 	}
 	srv.server = server.NewServer()
 	return srv
@@ -37,41 +43,98 @@ func (s *Server) Init(opts ...Option) {
 }
 
 func (s *Server) Start() error {
-	l, err := net.Listen("tcp", s.opts.Addr)
-	if err != nil {
-		return err
+	l := s.opts.Listener
+	if l == nil {
+		var err error
+		if l, err = net.Listen(s.opts.Network, s.opts.Addr); err != nil {
+			return err
+		}
 	}
-	a := l.Addr().String()
+	s.addr = l.Addr()
+	a := s.addr.String()
 	if s.opts.Tracing {
 		tracer := otel.Tracer("rpcx")
 		p := otelServerPlugin.NewOpenTelemetryPlugin(tracer, nil)
 		s.server.Plugins.Add(p)
 	}
-	s.register(a)
+	if s.opts.Metrics != nil {
+		s.server.Plugins.Add(NewMetricsPlugin(s.opts.Metrics))
+	}
+	s.server.Plugins.Add(NewRecoveryPlugin(s.opts.Metrics, s.opts.PanicReporter))
+	if err := s.register(a); err != nil {
+		_ = l.Close()
+		return err
+	}
 
 	if s.opts.InitRpcServer != nil {
 		if err := s.opts.InitRpcServer(s.server); err != nil {
+			_ = l.Close()
 			return err
 		}
 	}
 
+	if s.opts.TLSConfig != nil {
+		// rpcx does the handshake of *tls.Conn itself
+		l = tls.NewListener(l, s.opts.TLSConfig)
+	}
+
 	log.Infof("Server [RPCX] listening on %s", a)
 	go func() {
-		if err := s.server.ServeListener("tcp", l); err != nil {
-			log.Fatal(err.Error())
+		defer close(s.errc)
+		err := s.server.ServeListener(s.opts.Network, l)
+		if !errors.Is(err, server.ErrServerClosed) {
+			log.Errorf("Server [RPCX] serve error: %v", err)
+			s.errc <- err
 		}
 	}()
 	return nil
 }
 
+// Addr returns the address the server is bound to, nil before Start.
+func (s *Server) Addr() net.Addr {
+	return s.addr
+}
+
+// Err returns a channel that receives the error of the server when it
+// stops serving for another reason than Shutdown. It is closed when the
+// server stops serving.
+func (s *Server) Err() <-chan error {
+	return s.errc
+}
+
 func (s *Server) Stop() error {
+	return s.Shutdown(context.Background())
+}
+
+// Shutdown gracefully shuts down the server, waiting for in-flight
+// requests to finish until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
 	log.Info("Server [RPCX] stopping")
-	return s.server.Shutdown(context.Background())
+	return s.server.Shutdown(ctx)
 }
 
-func (s *Server) register(a string) {
-	if len(s.opts.EtcdAddr) == 0 {
-		return
+// Deregister removes the server from the registry, so that clients stop
+// routing new requests to it. It is safe to call more than once.
+func (s *Server) Deregister() error {
+	if s.registry == nil {
+		return nil
+	}
+	r := s.registry
+	s.registry = nil
+
+	// the plugin stays registered, removing it races with the in-flight
+	// requests that iterate the plugins. Without services, the Unregister
+	// of Shutdown does nothing.
+	log.Infof("Deregistering server: %s", r.ServiceAddress)
+	err := r.Stop()
+	r.Services = nil
+	return err
+}
+
+func (s *Server) register(a string) error {
+	// only tcp listeners are reachable from other hosts
+	if len(s.opts.EtcdAddr) == 0 || s.opts.Network != "tcp" {
+		return nil
 	}
 
 	var err error
@@ -79,8 +142,7 @@ func (s *Server) register(a string) {
 	if cnt := strings.Count(a, ":"); cnt >= 1 {
 		host, port, err = net.SplitHostPort(a)
 		if err != nil {
-			log.Fatal(err.Error())
-			return
+			return err
 		}
 	} else {
 		host = a
@@ -88,7 +150,7 @@ func (s *Server) register(a string) {
 
 	address, err := addr.Extract(host)
 	if err != nil {
-		log.Fatal(err.Error())
+		return err
 	}
 
 	if port != "" {
@@ -103,9 +165,11 @@ func (s *Server) register(a string) {
 	}
 	err = r.Start()
 	if err != nil {
-		log.Fatal(err.Error())
+		return err
 	}
 	s.server.Plugins.Add(r)
+	s.registry = r
 
 	log.Infof("Registering server: %s", address)
+	return nil
 }
@@ -0,0 +1,75 @@
+package fswatch
+
+import (
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/zmicro-team/zmicro/core/log"
+)
+
+// Watcher calls a func when files change.
+type Watcher struct {
+	w    *fsnotify.Watcher
+	done chan struct{}
+}
+
+// Watch calls onChange with the name of the event whenever one of files is
+// written, created or removed. Like viper, it watches the parent
+// directories, so that files replaced by a symlink swap, e.g. a kubernetes
+// ConfigMap or Secret, are noticed.
+func Watch(files []string, onChange func(name string)) (*Watcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]struct{}, len(files))
+	dirs := make(map[string]struct{}, len(files))
+	for _, f := range files {
+		f = filepath.Clean(f)
+		names[f] = struct{}{}
+		if real, err := filepath.EvalSymlinks(f); err == nil {
+			names[real] = struct{}{}
+		}
+		dirs[filepath.Dir(f)] = struct{}{}
+	}
+	for d := range dirs {
+		if err = w.Add(d); err != nil {
+			_ = w.Close()
+			return nil, err
+		}
+	}
+
+	fw := &Watcher{w: w, done: make(chan struct{})}
+	go func() {
+		defer close(fw.done)
+		for {
+			select {
+			case e, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				_, ours := names[filepath.Clean(e.Name)]
+				// ConfigMap and Secret updates swap the ..data symlink
+				symlink := filepath.Base(e.Name) == "..data"
+				if (ours || symlink) && e.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+					onChange(e.Name)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Errorf("fswatch: watch error: %v", err)
+			}
+		}
+	}()
+	return fw, nil
+}
+
+// Close stops watching and waits for the pending call of onChange.
+func (fw *Watcher) Close() error {
+	err := fw.w.Close()
+	<-fw.done
+	return err
+}
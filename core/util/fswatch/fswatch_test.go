@@ -0,0 +1,39 @@
+package fswatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "a.yaml")
+	if err := os.WriteFile(p, []byte("a: 1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := make(chan string, 10)
+	w, err := Watch([]string{p}, func(name string) { changed <- name })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// other files of the directory are ignored
+	if err := os.WriteFile(filepath.Join(dir, "b.yaml"), []byte("b: 1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte("a: 2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case name := <-changed:
+		if name != p {
+			t.Fatalf("got %s, want %s", name, p)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change")
+	}
+}
@@ -0,0 +1,202 @@
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zmicro-team/zmicro/core/log"
+	"github.com/zmicro-team/zmicro/core/util/fswatch"
+)
+
+// Reloader holds a certificate and a CA pool loaded from files, and loads
+// them again when the files change, so that rotated certificates are used
+// by new connections without a restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	cert atomic.Pointer[tls.Certificate]
+	pool atomic.Pointer[x509.CertPool]
+
+	mu      sync.Mutex
+	watcher *fswatch.Watcher
+}
+
+// NewReloader loads the certificate of certFile and keyFile and the CA of
+// caFile, and watches them. Each of them may be empty, but not only one of
+// certFile and keyFile.
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("tls: certFile and keyFile must be set together")
+	}
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	if err := r.watch(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the files again. On error the previous certificate and CA
+// are kept.
+func (r *Reloader) Reload() error {
+	var cert *tls.Certificate
+	if r.certFile != "" {
+		c, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("tls: load %s: %w", r.certFile, err)
+		}
+		cert = &c
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		b, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("tls: load %s: %w", r.caFile, err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return fmt.Errorf("tls: load %s: no certificate found", r.caFile)
+		}
+	}
+
+	if cert != nil {
+		r.cert.Store(cert)
+	}
+	if pool != nil {
+		r.pool.Store(pool)
+	}
+	return nil
+}
+
+// Certificate returns the current certificate, nil if there is no certFile.
+func (r *Reloader) Certificate() *tls.Certificate {
+	return r.cert.Load()
+}
+
+// CertPool returns the current CA pool, nil if there is no caFile.
+func (r *Reloader) CertPool() *x509.CertPool {
+	return r.pool.Load()
+}
+
+// ServerConfig returns a config for servers that presents the current
+// certificate. With a caFile it requires client certificates signed by the
+// current CA, that is mTLS. NextProtos offers h2 for http servers, it is
+// ignored by clients that do not use ALPN.
+func (r *Reloader) ServerConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.getCertificate()
+		},
+	}
+	if r.caFile != "" {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.ClientCAs = r.CertPool()
+		// ClientCAs is read per handshake, so the CA can be rotated too
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c := cfg.Clone()
+			c.GetConfigForClient = nil
+			c.ClientCAs = r.CertPool()
+			return c, nil
+		}
+	}
+	return cfg
+}
+
+// ClientConfig returns a config for clients that presents the current
+// certificate if any, and verifies the server against the current CA, or
+// the system roots without a caFile. serverName overrides the host name
+// verified, e.g. when dialing by ip.
+func (r *Reloader) ClientConfig(serverName string) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+	if r.certFile != "" {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.getCertificate()
+		}
+	}
+	if r.caFile != "" {
+		// RootCAs cannot change after the config is created, so the chain
+		// is verified here against the current CA instead
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("tls: no server certificate")
+			}
+			opts := x509.VerifyOptions{
+				DNSName:       cs.ServerName,
+				Roots:         r.CertPool(),
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, c := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(c)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	}
+	return cfg
+}
+
+func (r *Reloader) getCertificate() (*tls.Certificate, error) {
+	if c := r.cert.Load(); c != nil {
+		return c, nil
+	}
+	return nil, errors.New("tls: no certificate")
+}
+
+// watch reloads the files when they are written, created or removed.
+func (r *Reloader) watch() error {
+	var files []string
+	for _, f := range []string{r.certFile, r.keyFile, r.caFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	w, err := fswatch.Watch(files, func(name string) {
+		// the cert and the key may be written one after the other, the
+		// next event loads the matching pair
+		if err := r.Reload(); err != nil {
+			log.Warnf("tls: reload failed, keep the previous certificate: %v", err)
+		} else {
+			log.Infof("tls: reloaded, %s changed", name)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	r.watcher = w
+	return nil
+}
+
+// Close stops watching the files. It is safe to call more than once.
+func (r *Reloader) Close() error {
+	r.mu.Lock()
+	w := r.watcher
+	r.watcher = nil
+	r.mu.Unlock()
+	if w == nil {
+		return nil
+	}
+	return w.Close()
+}
@@ -0,0 +1,188 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newCA(t *testing.T) *testCA {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a certificate for localhost signed by ca to dir.
+func (ca *testCA) issue(t *testing.T, dir string, serial int64) (certFile, keyFile string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb, _ := x509.MarshalECPrivateKey(key)
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	write(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	write(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb}))
+	return certFile, keyFile
+}
+
+func write(t *testing.T, name string, b []byte) {
+	if err := os.WriteFile(name, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func serialOf(t *testing.T, c *tls.Certificate) int64 {
+	leaf, err := x509.ParseCertificate(c.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.SerialNumber.Int64()
+}
+
+// handshake returns the serial of the server certificate seen by the client.
+func handshake(t *testing.T, server, client *tls.Config) (*big.Int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer c.Close()
+		errc <- tls.Server(c, server).Handshake()
+	}()
+
+	cc, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	c := tls.Client(cc, client)
+	if err = c.Handshake(); err != nil {
+		return nil, err
+	}
+	// with TLS 1.3 the server verifies the client certificate after the
+	// client is done
+	if err = <-errc; err != nil {
+		return nil, err
+	}
+	return c.ConnectionState().PeerCertificates[0].SerialNumber, nil
+}
+
+func TestReloaderMutualTLS(t *testing.T) {
+	ca := newCA(t)
+	serverDir, clientDir := t.TempDir(), t.TempDir()
+	caFile := filepath.Join(serverDir, "ca.crt")
+	write(t, caFile, ca.pem)
+	certFile, keyFile := ca.issue(t, serverDir, 2)
+	clientCert, clientKey := ca.issue(t, clientDir, 3)
+
+	sr, err := NewReloader(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sr.Close()
+	cr, err := NewReloader(clientCert, clientKey, caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cr.Close()
+
+	serial, err := handshake(t, sr.ServerConfig(), cr.ClientConfig("localhost"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serial.Int64() != 2 {
+		t.Fatalf("serial = %v, want 2", serial)
+	}
+
+	// a client without certificate is rejected
+	anon, err := NewReloader("", "", caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer anon.Close()
+	if _, err = handshake(t, sr.ServerConfig(), anon.ClientConfig("localhost")); err == nil {
+		t.Fatal("handshake without client certificate succeeded")
+	}
+
+	// rotate the server certificate
+	ca.issue(t, serverDir, 4)
+	deadline := time.Now().Add(5 * time.Second)
+	for serialOf(t, sr.Certificate()) != 4 {
+		if time.Now().After(deadline) {
+			t.Fatal("certificate not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if serial, err = handshake(t, sr.ServerConfig(), cr.ClientConfig("localhost")); err != nil {
+		t.Fatal(err)
+	}
+	if serial.Int64() != 4 {
+		t.Fatalf("serial = %v, want 4", serial)
+	}
+}
+
+func TestReloaderKeepsCertificateOnError(t *testing.T) {
+	ca := newCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := ca.issue(t, dir, 2)
+	r, err := NewReloader(certFile, keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	write(t, certFile, []byte("broken"))
+	if err = r.Reload(); err == nil {
+		t.Fatal("Reload succeeded with a broken certificate")
+	}
+	if serialOf(t, r.Certificate()) != 2 {
+		t.Fatal("previous certificate not kept")
+	}
+}
@@ -17,6 +17,7 @@ func main() {
 	app := zmicro.New(
 		zmicro.InitRpcServer(InitRpcServer),
 		zmicro.InitHttpServer(InitHttpServer),
+		zmicro.InitNamedHttpServer("admin", InitAdminServer),
 	)
 
 	if err := app.Run(); err != nil {
@@ -52,6 +53,14 @@ func InitHttpServer(r *gin.Engine) error {
 	return nil
 }
 
+func InitAdminServer(r *gin.Engine) error {
+	r.GET("/ping", func(c *gin.Context) {
+		c.String(200, "pong")
+	})
+
+	return nil
+}
+
 type HttpGreeter struct{}
 
 func (s *HttpGreeter) SayHello(ctx context.Context, req *api.HelloRequest, rsp *api.HelloReply) error {
@@ -1,18 +1,50 @@
 package zmicro
 
 import (
+	"context"
+	"time"
+
 	"github.com/zmicro-team/zmicro/core/config"
+	"github.com/zmicro-team/zmicro/core/metrics"
+	"github.com/zmicro-team/zmicro/core/transport"
 	"github.com/zmicro-team/zmicro/core/transport/http"
 	"github.com/zmicro-team/zmicro/core/transport/rpc/server"
 )
 
 type BeforeFunc func() error
 
+type AfterFunc func() error
+
+// StopFunc is called during shutdown, after the servers have been drained.
+// ctx carries the shutdown deadline.
+type StopFunc func(ctx context.Context) error
+
 type Options struct {
-	InitRpcServer   server.InitRpcServerFunc
-	InitHttpServer  http.InitHttpServerFunc
+	InitRpcServer  server.InitRpcServerFunc
+	InitHttpServer http.InitHttpServerFunc
+	// named servers, keyed by the name under rpc.servers/http.servers in config
+	InitRpcServers  map[string]server.InitRpcServerFunc
+	InitHttpServers map[string]http.InitHttpServerFunc
 	ConfigCallbacks []func(config.IConfig)
-	Before          BeforeFunc
+	// ConfigOnChange can reject config reloads, see config.Config.OnChange.
+	ConfigOnChange []config.ChangeFunc
+	Before         BeforeFunc
+	// Metrics overrides the metrics created from the metrics config section.
+	Metrics *metrics.Metrics
+	// PanicReporter is called with the panics recovered by the servers.
+	PanicReporter transport.PanicReporter
+	// Components are started before the servers and stopped after them.
+	Components []Component
+	// OnStop hooks run in reverse registration order.
+	OnStop []StopFunc
+
+	// ShutdownTimeout bounds the drain of in-flight requests and the
+	// OnStop hooks. zero means use app.shutdownTimeout in config.
+	ShutdownTimeout time.Duration
+	// GracePeriod is waited after deregistering from the registry and
+	// before draining, so that clients observe the change.
+	// zero means use app.gracePeriod in config.
+	GracePeriod time.Duration
 }
 
 type Option func(*Options)
@@ -39,14 +71,89 @@ func InitHttpServer(f http.InitHttpServerFunc) Option {
 	}
 }
 
+// InitNamedRpcServer sets the init function of the rpc server configured
+// by rpc.servers.<name>.
+func InitNamedRpcServer(name string, f server.InitRpcServerFunc) Option {
+	return func(o *Options) {
+		if o.InitRpcServers == nil {
+			o.InitRpcServers = make(map[string]server.InitRpcServerFunc)
+		}
+		o.InitRpcServers[name] = f
+	}
+}
+
+// InitNamedHttpServer sets the init function of the http server configured
+// by http.servers.<name>.
+func InitNamedHttpServer(name string, f http.InitHttpServerFunc) Option {
+	return func(o *Options) {
+		if o.InitHttpServers == nil {
+			o.InitHttpServers = make(map[string]http.InitHttpServerFunc)
+		}
+		o.InitHttpServers[name] = f
+	}
+}
+
 func ConfigCallbacks(f ...func(config.IConfig)) Option {
 	return func(o *Options) {
 		o.ConfigCallbacks = f
 	}
 }
 
+func ConfigOnChange(f ...config.ChangeFunc) Option {
+	return func(o *Options) {
+		o.ConfigOnChange = append(o.ConfigOnChange, f...)
+	}
+}
+
 func Before(f BeforeFunc) Option {
 	return func(o *Options) {
 		o.Before = f
 	}
 }
+
+func After(f ...AfterFunc) Option {
+	return func(o *Options) {
+		for _, fn := range f {
+			fn := fn
+			o.OnStop = append(o.OnStop, func(context.Context) error {
+				return fn()
+			})
+		}
+	}
+}
+
+func OnStop(f ...StopFunc) Option {
+	return func(o *Options) {
+		o.OnStop = append(o.OnStop, f...)
+	}
+}
+
+func ShutdownTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.ShutdownTimeout = d
+	}
+}
+
+func GracePeriod(d time.Duration) Option {
+	return func(o *Options) {
+		o.GracePeriod = d
+	}
+}
+
+func Components(c ...Component) Option {
+	return func(o *Options) {
+		o.Components = append(o.Components, c...)
+	}
+}
+
+func Metrics(m *metrics.Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = m
+	}
+}
+
+func PanicReporter(r transport.PanicReporter) Option {
+	return func(o *Options) {
+		o.PanicReporter = r
+	}
+}
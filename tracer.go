@@ -0,0 +1,110 @@
+package zmicro
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+
+	"github.com/zmicro-team/zmicro/core/util/env"
+)
+
+const (
+	ExporterOtlpGrpc = "otlp-grpc"
+	ExporterOtlpHttp = "otlp-http"
+	ExporterJaeger   = "jaeger"
+	ExporterStdout   = "stdout"
+	ExporterNone     = "none"
+)
+
+// setTracerProvider installs the global tracer provider and propagator
+// configured by the tracer section.
+func setTracerProvider(zc *zconfig) (*trace.TracerProvider, error) {
+	ratio := 1.0
+	if zc.Tracer.SampleRatio != nil {
+		ratio = *zc.Tracer.SampleRatio
+	}
+	opts := []trace.TracerProviderOption{
+		trace.WithSampler(trace.ParentBased(trace.TraceIDRatioBased(ratio))),
+		trace.WithResource(newResource(zc)),
+	}
+
+	exp, err := newExporter(zc)
+	if err != nil {
+		return nil, err
+	}
+	if exp != nil {
+		opts = append(opts, trace.WithBatcher(exp))
+	}
+	tp := trace.NewTracerProvider(opts...)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(
+		propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		),
+	)
+
+	return tp, nil
+}
+
+func newExporter(zc *zconfig) (trace.SpanExporter, error) {
+	exporter := zc.Tracer.Exporter
+	if exporter == "" {
+		exporter = ExporterNone
+		if zc.Tracer.Addr != "" {
+			exporter = ExporterJaeger
+		}
+	}
+
+	switch exporter {
+	case ExporterOtlpGrpc:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(zc.Tracer.Addr)}
+		if zc.Tracer.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(context.Background(), opts...)
+	case ExporterOtlpHttp:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(zc.Tracer.Addr)}
+		if zc.Tracer.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(context.Background(), opts...)
+	case ExporterJaeger:
+		return jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(zc.Tracer.Addr)))
+	case ExporterStdout:
+		return stdouttrace.New()
+	case ExporterNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown tracer exporter %q", exporter)
+	}
+}
+
+func newResource(zc *zconfig) *resource.Resource {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(zc.App.Name),
+		semconv.DeploymentEnvironmentKey.String(env.Get().String()),
+	}
+	if zc.App.Version != "" {
+		attrs = append(attrs, semconv.ServiceVersionKey.String(zc.App.Version))
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		attrs = append(attrs, semconv.HostNameKey.String(hostname))
+	}
+	for k, v := range zc.Tracer.Attributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+}
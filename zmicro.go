@@ -1,46 +1,77 @@
 package zmicro
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"os/signal"
+	stdpath "path"
+	"sort"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/zmicro-team/zmicro/core/admin"
 	"github.com/zmicro-team/zmicro/core/config"
+	"github.com/zmicro-team/zmicro/core/config/etcd"
 	"github.com/zmicro-team/zmicro/core/log"
+	"github.com/zmicro-team/zmicro/core/log/writer/async"
+	"github.com/zmicro-team/zmicro/core/log/writer/loki"
+	"github.com/zmicro-team/zmicro/core/log/writer/syslog"
+	"github.com/zmicro-team/zmicro/core/metrics"
 	"github.com/zmicro-team/zmicro/core/transport/http"
 	"github.com/zmicro-team/zmicro/core/transport/rpc/server"
 	"github.com/zmicro-team/zmicro/core/util/env"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/jaeger"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
+	ztls "github.com/zmicro-team/zmicro/core/util/tls"
 	"go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-var cfgFile string
+const (
+	defaultShutdownTimeout = 10 * time.Second
+	// envPrefix is the prefix of the environment variables that override
+	// the config, e.g. ZMICRO_HTTP_ADDR
+	envPrefix = "ZMICRO"
+	// configKeyEnv is the environment variable of the key of the encrypted
+	// config values
+	configKeyEnv = "ZMICRO_CONFIG_KEY"
+	// metricsPath serves the metrics on the admin server, or on the http
+	// server without one
+	metricsPath = "/metrics"
+)
 
 func init() {
-	flag.StringVar(&cfgFile, "config", "config.yaml", "config file")
+	flag.String("config", "config.yaml", "config file")
 }
 
 type App struct {
-	opts       Options
-	zc         *zconfig
-	rpcServer  *server.Server
-	httpServer *http.Server
+	opts        Options
+	zc          *zconfig
+	rpcServers  []*server.Server
+	httpServers []*http.Server
+	admin       *admin.Admin
+	tp          *trace.TracerProvider
+	metrics     *metrics.Metrics
+	reloaders   []*ztls.Reloader
 }
 
 type zconfig struct {
 	App struct {
-		Mode string
-		Name string
+		Mode            string
+		Name            string `validate:"required"`
+		Version         string
+		ShutdownTimeout time.Duration
+		GracePeriod     time.Duration
 	}
 	Logger struct {
 		Level      string `json:"level"`
@@ -49,42 +80,198 @@ type zconfig struct {
 		MaxBackups int    `json:"maxBackups"`
 		MaxAge     int    `json:"maxAge"`
 		Compress   bool   `json:"compress"`
+		// Sampling is disabled if tick is 0, see log.WithSampling
+		Sampling struct {
+			Tick       time.Duration `json:"tick"`
+			First      int           `json:"first"`
+			Thereafter int           `json:"thereafter"`
+		} `json:"sampling"`
+		// RateLimit per message is disabled if rate is 0, see log.WithRateLimit
+		RateLimit struct {
+			Rate  float64 `json:"rate"`
+			Burst int     `json:"burst"`
+		} `json:"rateLimit"`
+		// Dedup is the window to collapse repeated messages, see log.WithDedup
+		Dedup time.Duration `json:"dedup"`
+		// Sinks replace the default outputs, see log.Sink
+		Sinks []struct {
+			// Output is stderr, stdout, a file rotated like filename, a
+			// syslog address like udp://127.0.0.1:514, tcp://host:port,
+			// unix:///dev/log or unixgram:///dev/log, or a loki push url. default filename
+			Output string `json:"output"`
+			// Level is the minimum level of the sink, default level
+			Level string `json:"level"`
+			// Encoding is json, console or color
+			Encoding string `json:"encoding"`
+		} `json:"sinks"`
 	}
 	Http struct {
 		Addr string
+		TLS  tlsConfig
+		// limits of all http servers but admin, zero keeps the defaults of
+		// the http options
+		ReadTimeout       time.Duration
+		ReadHeaderTimeout time.Duration
+		WriteTimeout      time.Duration
+		IdleTimeout       time.Duration
+		MaxHeaderBytes    int
+		MaxConns          int
+		// H2C serves HTTP/2 without tls
+		H2C bool
+		// named servers, see InitNamedHttpServer
+		Servers map[string]struct {
+			Addr string
+			// TLS defaults to http.tls unless disabled
+			TLS tlsConfig
+		}
 	}
 	Rpc struct {
 		Addr string
+		TLS  tlsConfig
+		// named servers, see InitNamedRpcServer
+		Servers map[string]struct {
+			Network string
+			Addr    string
+			// TLS defaults to rpc.tls unless disabled
+			TLS tlsConfig
+		}
 	}
 	Tracer struct {
+		// Exporter is one of otlp-grpc, otlp-http, jaeger, stdout and none.
+		// default jaeger if Addr is set, otherwise none
+		Exporter string
+		Addr     string
+		Insecure bool
+		// SampleRatio samples root spans, default 1
+		SampleRatio *float64
+		// Attributes are extra resource attributes
+		Attributes map[string]string
+	}
+	Metrics struct {
+		Enabled   bool
+		Namespace string
+	}
+	Admin struct {
+		// Addr enables a dedicated admin http server
 		Addr string
 	}
 	Registry struct {
 		BasePath       string
 		EtcdAddr       []string
 		UpdateInterval int
+		// WatchConfig merges the keys under <basePath>/config/<app.name>
+		// into the config and watches them
+		WatchConfig bool
 	}
 }
 
+// tlsConfig enables tls on a server when CertFile is set, and mTLS when
+// ClientCAFile is set too. The files are reloaded when they change.
+type tlsConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	// Disabled serves a named server without tls even when the default
+	// tls is set
+	Disabled bool
+}
+
+// New creates an App from the file given by the -config command line flag.
+// It exits the process on any error, use NewWithConfig to handle errors.
 func New(opts ...Option) *App {
-	options := newOptions(opts...)
-	flag.Parse()
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	cfgFile := flag.Lookup("config").Value.String()
 	_, err := os.Stat(cfgFile)
 	if os.IsNotExist(err) {
 		log.Fatal("config file not exists")
 	}
 
-	c := config.New(config.Path(cfgFile), config.Callbacks(options.ConfigCallbacks...))
-	config.ResetDefault(c)
+	c, err := LoadConfig(cfgFile)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
 
-	zc := &zconfig{}
-	if err = config.Default().Unmarshal(zc); err != nil {
+	app, err := NewWithConfig(c, opts...)
+	if err != nil {
+		// stop the watches of the config, e.g. the etcd source
+		if cl, ok := c.(io.Closer); ok {
+			_ = cl.Close()
+		}
 		log.Fatal(err.Error())
 	}
-	fmt.Println(zc)
-	if zc.App.Name == "" {
-		log.Fatal("配置项app.name不能为空")
+	return app
+}
+
+// LoadConfig loads the config the way New does, merging from lowest to
+// highest precedence:
+//   - the file at path
+//   - its env file, e.g. config.develop.yaml
+//   - the keys under <registry.basePath>/config/<app.name> in etcd, if
+//     registry.watchConfig is set
+//   - environment variables, e.g. ZMICRO_HTTP_ADDR for http.addr
+//
+// The enc:AES256:... values are decrypted with the base64 encoded key in
+// the ZMICRO_CONFIG_KEY environment variable.
+func LoadConfig(path string, opts ...config.Option) (config.IConfig, error) {
+	base := []config.Option{
+		config.Path(path),
+		config.EnvFile(true),
+		config.EnvPrefix(envPrefix),
+	}
+	if k := os.Getenv(configKeyEnv); k != "" {
+		key, err := base64.StdEncoding.DecodeString(k)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", configKeyEnv, err)
+		}
+		base = append(base, config.SecretResolvers(config.AESSecret(key)))
+	}
+
+	// the etcd source is configured by the local layers
+	local, err := config.Load(append(base, config.AutoReload(false))...)
+	if err != nil {
+		return nil, err
+	}
+	zc := &zconfig{}
+	if err = local.Unmarshal(zc); err != nil {
+		return nil, err
+	}
+	if zc.Registry.WatchConfig && len(zc.Registry.EtcdAddr) > 0 {
+		src, err := etcd.New(
+			etcd.Endpoints(zc.Registry.EtcdAddr),
+			etcd.Prefix(stdpath.Join(zc.Registry.BasePath, "config", zc.App.Name)),
+		)
+		if err != nil {
+			return nil, err
+		}
+		base = append(base, config.Sources(src))
+	}
+
+	return config.Load(append(base, opts...)...)
+}
+
+// NewWithConfig creates an App from c. It neither parses command line flags
+// nor exits the process, so it can be used in tests and in tools that have
+// their own flags. ConfigCallbacks are called with c, and again whenever
+// it changes.
+func NewWithConfig(c config.IConfig, opts ...Option) (*App, error) {
+	options := newOptions(opts...)
+	if w, ok := c.(config.Watcher); ok {
+		w.OnChange(options.ConfigOnChange...)
+		w.AddCallback(options.ConfigCallbacks...)
+	}
+	for _, f := range options.ConfigCallbacks {
+		f(c)
+	}
+
+	config.ResetDefault(c)
+
+	bound, err := config.Bind[zconfig]("")
+	if err != nil {
+		return nil, err
 	}
+	zc := &bound
 
 	env.Set(zc.App.Mode)
 
@@ -92,111 +279,355 @@ func New(opts ...Option) *App {
 	if err != nil {
 		level = log.InfoLevel
 	}
-	if env.IsDevelop() {
-		w := &lumberjack.Logger{
-			Filename:   zc.Logger.Filename,
-			MaxSize:    zc.Logger.MaxSize,
-			MaxBackups: zc.Logger.MaxBackups,
-			MaxAge:     zc.Logger.MaxAge,
-			Compress:   zc.Logger.Compress,
+	l, err := newLogger(zc, level)
+	if err != nil {
+		return nil, err
+	}
+	log.ResetDefault(l)
+	// third-party slog output goes to the same sinks
+	log.SetSlogDefault(l)
+	if l.Enabled(log.DebugLevel) {
+		if m, err := config.Dump(c); err == nil {
+			log.Debugf("config: %v", m)
 		}
-		l := log.NewTee([]io.Writer{os.Stderr, w}, level, log.WithCaller(true))
-		log.ResetDefault(l)
-	} else {
-		w := &lumberjack.Logger{
-			Filename:   zc.Logger.Filename,
+	}
+	watchLogLevel(c, zc.Logger.Level)
+
+	app := &App{
+		opts:  options,
+		zc:    zc,
+		admin: admin.New(admin.Name(zc.App.Name), admin.Config(c)),
+	}
+	if app.opts.ShutdownTimeout <= 0 {
+		app.opts.ShutdownTimeout = zc.App.ShutdownTimeout
+	}
+	if app.opts.ShutdownTimeout <= 0 {
+		app.opts.ShutdownTimeout = defaultShutdownTimeout
+	}
+	if app.opts.GracePeriod <= 0 {
+		app.opts.GracePeriod = zc.App.GracePeriod
+	}
+
+	app.metrics = app.opts.Metrics
+	if app.metrics == nil && zc.Metrics.Enabled {
+		if app.metrics, err = metrics.New(metrics.Namespace(zc.Metrics.Namespace)); err != nil {
+			return nil, err
+		}
+	}
+
+	tracing := true // always true for trace id
+	if app.tp, err = setTracerProvider(zc); err != nil {
+		return nil, err
+	}
+
+	if err = app.initRpcServers(tracing); err != nil {
+		app.release()
+		return nil, err
+	}
+	if err = app.initHttpServers(tracing); err != nil {
+		app.release()
+		return nil, err
+	}
+
+	return app, nil
+}
+
+// newLogger creates the logger of the logger section, writing to its sinks
+// if any, otherwise to logger.filename, teed with stderr in develop mode.
+func newLogger(zc *zconfig, level log.Level) (*log.Logger, error) {
+	newFile := func(filename string) io.Writer {
+		return &lumberjack.Logger{
+			Filename:   filename,
 			MaxSize:    zc.Logger.MaxSize,
 			MaxBackups: zc.Logger.MaxBackups,
 			MaxAge:     zc.Logger.MaxAge,
 			Compress:   zc.Logger.Compress,
 		}
-		l := log.New(w, level, log.WithCaller(true))
-		log.ResetDefault(l)
 	}
 
-	app := &App{
-		opts: options,
-		zc:   zc,
+	if len(zc.Logger.Sinks) == 0 {
+		w := newFile(zc.Logger.Filename)
+		if env.IsDevelop() {
+			return log.NewTee([]io.Writer{os.Stderr, w}, level, loggerOptions(zc)...), nil
+		}
+		return log.New(w, level, loggerOptions(zc)...), nil
 	}
 
-	tracing := true // always true for trace id
-	if zc.Tracer.Addr != "" {
-		setTracerProvider(zc.Tracer.Addr, zc.App.Name)
-	} else {
-		setNoExporterTracerProvider(zc.App.Name)
-	}
-
-	if app.opts.InitRpcServer != nil {
-		app.rpcServer = server.NewServer(
-			server.Name(zc.App.Name),
-			server.Addr(zc.Rpc.Addr),
-			server.BasePath(zc.Registry.BasePath),
-			server.UpdateInterval(zc.Registry.UpdateInterval),
-			server.EtcdAddr(zc.Registry.EtcdAddr),
+	sinks := make([]log.Sink, 0, len(zc.Logger.Sinks))
+	for i, s := range zc.Logger.Sinks {
+		sink := log.Sink{Encoding: s.Encoding}
+		switch s.Encoding {
+		case "", log.EncodingJSON, log.EncodingConsole, log.EncodingColor:
+		default:
+			return nil, fmt.Errorf("配置项logger.sinks.%d.encoding无效: %s", i, s.Encoding)
+		}
+		u, _ := url.Parse(s.Output)
+		switch {
+		case s.Output == "stderr":
+			sink.Writer = os.Stderr
+		case s.Output == "stdout":
+			sink.Writer = os.Stdout
+		case s.Output == "":
+			sink.Writer = newFile(zc.Logger.Filename)
+		case u == nil || u.Scheme == "":
+			sink.Writer = newFile(s.Output)
+		case isSyslogScheme(u.Scheme):
+			addr := u.Host
+			if u.Scheme == "unix" || u.Scheme == "unixgram" {
+				addr = u.Path
+			}
+			w := syslog.NewWriter(syslog.Network(u.Scheme), syslog.Addr(addr), syslog.AppName(zc.App.Name))
+			// do not block logging on the network
+			sink.Writer = async.NewWriter(w, async.Drop(async.DropNew))
+		case u.Scheme == "http" || u.Scheme == "https":
+			sink.Writer = loki.NewWriter(loki.URL(s.Output), loki.Labels(map[string]string{"app": zc.App.Name}))
+		default:
+			return nil, fmt.Errorf("配置项logger.sinks.%d.output无效: %s", i, s.Output)
+		}
+		if s.Level != "" {
+			lv, err := zapcore.ParseLevel(s.Level)
+			if err != nil {
+				return nil, fmt.Errorf("配置项logger.sinks.%d.level无效: %w", i, err)
+			}
+			sink.Level = lv
+		}
+		sinks = append(sinks, sink)
+	}
+	return log.NewWithSinks(sinks, level, loggerOptions(zc)...), nil
+}
+
+// isSyslogScheme reports whether a sink output with scheme is a syslog
+// address.
+func isSyslogScheme(scheme string) bool {
+	switch scheme {
+	case "udp", "tcp", "tcp4", "tcp6", "unix", "unixgram":
+		return true
+	}
+	return false
+}
+
+func loggerOptions(zc *zconfig) []log.Option {
+	opts := []log.Option{log.WithCaller(true)}
+	if s := zc.Logger.Sampling; s.Tick > 0 {
+		opts = append(opts, log.WithSampling(s.Tick, s.First, s.Thereafter))
+	}
+	if r := zc.Logger.RateLimit; r.Rate > 0 {
+		opts = append(opts, log.WithRateLimit(r.Rate, r.Burst))
+	}
+	if zc.Logger.Dedup > 0 {
+		opts = append(opts, log.WithDedup(zc.Logger.Dedup))
+	}
+	return opts
+}
+
+// watchLogLevel re-applies logger.level when the config changes. The level
+// is only touched when logger.level itself changes, so a level set through
+// the admin endpoint survives unrelated config edits.
+func watchLogLevel(c config.IConfig, current string) {
+	w, ok := c.(config.Watcher)
+	if !ok {
+		return
+	}
+	var mu sync.Mutex
+	w.AddCallback(func(c config.IConfig) {
+		s := c.GetString("logger.level")
+
+		mu.Lock()
+		defer mu.Unlock()
+		if s == current {
+			return
+		}
+		current = s
+
+		level, err := zapcore.ParseLevel(s)
+		if err != nil {
+			log.Warnf("invalid logger.level %q: %v", s, err)
+			return
+		}
+		log.Default().SetLevel(level)
+		log.Infof("log level changed to %s", level)
+	})
+}
+
+// initRpcServers creates the default rpc server configured by rpc.addr and
+// the named ones configured by rpc.servers.<name>.
+func (a *App) initRpcServers(tracing bool) error {
+	newServer := func(key, network, addr string, tc tlsConfig, f server.InitRpcServerFunc) error {
+		if network == "" {
+			network = "tcp"
+		}
+		if tc.CertFile == "" && !tc.Disabled {
+			key, tc = "rpc.tls", a.zc.Rpc.TLS
+		}
+		cfg, err := a.serverTLS(key, tc)
+		if err != nil {
+			return err
+		}
+		s := server.NewServer(
+			server.Name(a.zc.App.Name),
+			server.Network(network),
+			server.Addr(addr),
+			server.BasePath(a.zc.Registry.BasePath),
+			server.UpdateInterval(a.zc.Registry.UpdateInterval),
+			server.EtcdAddr(a.zc.Registry.EtcdAddr),
 			server.Tracing(tracing),
+			server.Metrics(a.metrics),
+			server.PanicReporter(a.opts.PanicReporter),
+			server.TLSConfig(cfg),
 		)
-		app.rpcServer.Init(server.InitRpcServer(app.opts.InitRpcServer))
+		s.Init(server.InitRpcServer(f))
+		a.rpcServers = append(a.rpcServers, s)
+		return nil
+	}
+
+	if a.opts.InitRpcServer != nil {
+		if err := newServer("rpc.tls", "tcp", a.zc.Rpc.Addr, a.zc.Rpc.TLS, a.opts.InitRpcServer); err != nil {
+			return err
+		}
+	}
+	for _, name := range sortedKeys(a.opts.InitRpcServers) {
+		c, ok := a.zc.Rpc.Servers[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("配置项rpc.servers.%s不能为空", name)
+		}
+		key := "rpc.servers." + name + ".tls"
+		if err := newServer(key, c.Network, c.Addr, c.TLS, a.opts.InitRpcServers[name]); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// initHttpServers creates the default http server configured by http.addr
+// and the named ones configured by http.servers.<name>. Every server has
+// its own gin engine, so middlewares are not shared between them.
+func (a *App) initHttpServers(tracing bool) error {
 	mode := "debug"
 	if env.IsProduct() || env.IsStaging() {
 		mode = "release"
 	}
-	if app.opts.InitHttpServer != nil {
-		app.httpServer = http.NewServer(
-			http.Name(zc.App.Name),
-			http.Addr(zc.Http.Addr),
+	newServer := func(key, addr string, tc tlsConfig, f http.InitHttpServerFunc, opts ...http.Option) error {
+		if tc.CertFile == "" && !tc.Disabled {
+			key, tc = "http.tls", a.zc.Http.TLS
+		}
+		cfg, err := a.serverTLS(key, tc)
+		if err != nil {
+			return err
+		}
+		s := http.NewServer(
+			http.Name(a.zc.App.Name),
+			http.Addr(addr),
 			http.Mode(mode),
 			http.Tracing(tracing),
+			http.Metrics(a.metrics),
+			http.PanicReporter(a.opts.PanicReporter),
+			http.TLSConfig(cfg),
 		)
-		app.httpServer.Init(http.InitHttpServer(app.opts.InitHttpServer))
+		s.Init(a.httpLimits()...)
+		s.Init(opts...)
+		s.Init(http.InitHttpServer(f))
+		a.httpServers = append(a.httpServers, s)
+		return nil
 	}
 
-	return app
+	if a.opts.InitHttpServer != nil {
+		var opts []http.Option
+		if a.zc.Admin.Addr == "" {
+			opts = append(opts, http.MetricsPath(metricsPath))
+		}
+		if err := newServer("http.tls", a.zc.Http.Addr, a.zc.Http.TLS, a.opts.InitHttpServer, opts...); err != nil {
+			return err
+		}
+	}
+	// the admin server stays plain, probes do not have client certificates
+	if a.zc.Admin.Addr != "" {
+		s := http.NewServer(
+			http.Name(a.zc.App.Name),
+			http.Addr(a.zc.Admin.Addr),
+			http.Mode(mode),
+			http.Metrics(a.metrics),
+			http.MetricsPath(metricsPath),
+			http.PanicReporter(a.opts.PanicReporter),
+		)
+		s.Init(http.InitHttpServer(func(r *gin.Engine) error {
+			a.admin.Register(r)
+			return nil
+		}))
+		a.httpServers = append(a.httpServers, s)
+	}
+	for _, name := range sortedKeys(a.opts.InitHttpServers) {
+		c, ok := a.zc.Http.Servers[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("配置项http.servers.%s不能为空", name)
+		}
+		key := "http.servers." + name + ".tls"
+		if err := newServer(key, c.Addr, c.TLS, a.opts.InitHttpServers[name]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func setTracerProvider(endpoint string, name string) *trace.TracerProvider {
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(endpoint)))
-	if err != nil {
-		log.Fatal(err.Error())
+// httpLimits returns the options of the limits set in the http section.
+func (a *App) httpLimits() []http.Option {
+	c := a.zc.Http
+	var opts []http.Option
+	if c.ReadTimeout > 0 {
+		opts = append(opts, http.ReadTimeout(c.ReadTimeout))
 	}
-	tp := trace.NewTracerProvider(
-		trace.WithSampler(trace.AlwaysSample()),
-		trace.WithBatcher(exp),
-		trace.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String(name),
-		)),
-	)
-
-	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(
-		propagation.NewCompositeTextMapPropagator(
-			propagation.TraceContext{},
-			propagation.Baggage{},
-		),
-	)
+	if c.ReadHeaderTimeout > 0 {
+		opts = append(opts, http.ReadHeaderTimeout(c.ReadHeaderTimeout))
+	}
+	if c.WriteTimeout > 0 {
+		opts = append(opts, http.WriteTimeout(c.WriteTimeout))
+	}
+	if c.IdleTimeout > 0 {
+		opts = append(opts, http.IdleTimeout(c.IdleTimeout))
+	}
+	if c.MaxHeaderBytes > 0 {
+		opts = append(opts, http.MaxHeaderBytes(c.MaxHeaderBytes))
+	}
+	if c.MaxConns > 0 {
+		opts = append(opts, http.MaxConns(c.MaxConns))
+	}
+	return append(opts, http.H2C(c.H2C))
+}
 
-	return tp
+// serverTLS returns the server tls config of tc, nil if tc has no cert.
+// key is the config key of tc, for errors.
+func (a *App) serverTLS(key string, tc tlsConfig) (*tls.Config, error) {
+	if tc.Disabled || tc.CertFile == "" {
+		return nil, nil
+	}
+	r, err := ztls.NewReloader(tc.CertFile, tc.KeyFile, tc.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("配置项%s无效: %w", key, err)
+	}
+	a.reloaders = append(a.reloaders, r)
+	return r.ServerConfig(), nil
 }
 
-func setNoExporterTracerProvider(name string) *trace.TracerProvider {
-	tp := trace.NewTracerProvider(
-		trace.WithSampler(trace.AlwaysSample()),
-		trace.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String(name),
-		)),
-	)
+func (a *App) closeReloaders() {
+	for _, r := range a.reloaders {
+		_ = r.Close()
+	}
+}
 
-	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(
-		propagation.NewCompositeTextMapPropagator(
-			propagation.TraceContext{},
-			propagation.Baggage{},
-		),
-	)
+// release frees what NewWithConfig acquired when a later step fails.
+func (a *App) release() {
+	a.closeReloaders()
+	if a.tp != nil {
+		_ = a.tp.Shutdown(context.Background())
+	}
+}
 
-	return tp
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func (a *App) Run() error {
@@ -205,29 +636,179 @@ func (a *App) Run() error {
 			return err
 		}
 	}
-	if a.rpcServer != nil {
-		if err := a.rpcServer.Start(); err != nil {
-			return err
+	if err := a.start(); err != nil {
+		return err
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGKILL)
+	select {
+	case sig := <-ch:
+		log.Infof("received signal %s", sig)
+	case err := <-a.serveErrors():
+		// one server is down, stop the others too
+		return errors.Join(err, a.shutdown())
+	}
+
+	return a.shutdown()
+}
+
+// serveErrors returns a channel that receives the first error of the
+// servers that stop serving unexpectedly.
+func (a *App) serveErrors() <-chan error {
+	errc := make(chan error, len(a.rpcServers)+len(a.httpServers))
+	forward := func(name string, c <-chan error) {
+		for err := range c {
+			errc <- fmt.Errorf("%s serve: %w", name, err)
 		}
 	}
+	for _, s := range a.rpcServers {
+		go forward("rpc", s.Err())
+	}
+	for _, s := range a.httpServers {
+		go forward("http", s.Err())
+	}
+	return errc
+}
 
-	if a.httpServer != nil {
-		if err := a.httpServer.Start(); err != nil {
-			return err
+// Admin returns the admin endpoints of the app. Use it to register
+// readiness checks, or to mount the endpoints on a server of your own:
+//
+//	app.Admin().Register(r.Group("/admin"))
+func (a *App) Admin() *admin.Admin {
+	return a.admin
+}
+
+// Metrics returns the metrics of the app, nil if metrics are disabled.
+// Pass it to http and rpc clients to record outbound calls.
+func (a *App) Metrics() *metrics.Metrics {
+	return a.metrics
+}
+
+// start starts the components and then the servers. When one of them
+// fails, everything started before it is stopped in reverse order.
+func (a *App) start() error {
+	ctx := context.Background()
+
+	n := 0
+	var stops []func(context.Context) error
+	rollback := func(err error) error {
+		ctx, cancel := context.WithTimeout(context.Background(), a.opts.ShutdownTimeout)
+		defer cancel()
+		errs := []error{err}
+		for i := len(stops) - 1; i >= 0; i-- {
+			errs = append(errs, stops[i](ctx))
 		}
+		errs = append(errs, a.stopComponents(ctx, n))
+		a.closeReloaders()
+		return errors.Join(errs...)
 	}
 
-	ch := make(chan os.Signal, 1)
-	signal.Notify(ch, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGKILL)
-	log.Infof("received signal %s", <-ch)
+	for _, c := range a.opts.Components {
+		if err := c.Start(ctx); err != nil {
+			return rollback(fmt.Errorf("start component %T: %w", c, err))
+		}
+		n++
+	}
+	for _, s := range a.rpcServers {
+		if err := s.Start(); err != nil {
+			return rollback(err)
+		}
+		s := s
+		stops = append(stops, func(ctx context.Context) error {
+			return errors.Join(s.Deregister(), s.Shutdown(ctx))
+		})
+	}
+	for _, s := range a.httpServers {
+		if err := s.Start(); err != nil {
+			return rollback(err)
+		}
+		stops = append(stops, s.Shutdown)
+	}
+	a.admin.SetReady(true)
+	return nil
+}
+
+// shutdown stops the app in order:
+//  1. fail readiness and deregister from the registry
+//  2. wait out the grace period
+//  3. drain all http and rpc servers under one shared deadline
+//  4. stop components in reverse order
+//  5. run OnStop hooks in reverse order
+//  6. flush the tracer provider
+func (a *App) shutdown() error {
+	var errs []error
 
-	if a.rpcServer != nil {
-		_ = a.rpcServer.Stop()
+	a.admin.SetReady(false)
+
+	for _, s := range a.rpcServers {
+		if err := s.Deregister(); err != nil {
+			errs = append(errs, fmt.Errorf("deregister: %w", err))
+		}
 	}
 
-	if a.httpServer != nil {
-		_ = a.httpServer.Stop()
+	if a.opts.GracePeriod > 0 {
+		log.Infof("waiting %s before draining", a.opts.GracePeriod)
+		time.Sleep(a.opts.GracePeriod)
 	}
 
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), a.opts.ShutdownTimeout)
+	defer cancel()
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	drain := func(name string, f func(context.Context) error) {
+		defer wg.Done()
+		if err := f(ctx); err != nil {
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("%s shutdown: %w", name, err))
+			mu.Unlock()
+		}
+	}
+	for _, s := range a.httpServers {
+		wg.Add(1)
+		go drain("http", s.Shutdown)
+	}
+	for _, s := range a.rpcServers {
+		wg.Add(1)
+		go drain("rpc", s.Shutdown)
+	}
+	wg.Wait()
+	a.closeReloaders()
+
+	if err := a.stopComponents(ctx, len(a.opts.Components)); err != nil {
+		errs = append(errs, err)
+	}
+
+	for i := len(a.opts.OnStop) - 1; i >= 0; i-- {
+		if err := a.opts.OnStop[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// flush spans still buffered in the batcher
+	if a.tp != nil {
+		if err := a.tp.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("tracer shutdown: %w", err))
+		}
+	}
+
+	// flush the buffered log writers, the error is ignored as syncing
+	// stderr fails on most platforms. The remote sinks may retry for long,
+	// so the flush does not outlive the shutdown timeout.
+	synced := make(chan struct{})
+	l := log.Default()
+	go func() {
+		defer close(synced)
+		_ = l.Sync()
+	}()
+	select {
+	case <-synced:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("log sync: %w", ctx.Err()))
+	}
+
+	return errors.Join(errs...)
 }
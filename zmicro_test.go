@@ -0,0 +1,505 @@
+package zmicro
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	nethttp "net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	rpcxServer "github.com/smallnest/rpcx/server"
+	"go.opentelemetry.io/otel"
+
+	"github.com/zmicro-team/zmicro/core/config"
+	"github.com/zmicro-team/zmicro/core/log"
+	"github.com/zmicro-team/zmicro/core/metrics"
+	"github.com/zmicro-team/zmicro/core/transport/http"
+)
+
+func loadConfig(t *testing.T, content string) config.IConfig {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := config.Load(config.Path(p), config.AutoReload(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+type recorder struct {
+	name     string
+	events   *[]string
+	startErr error
+}
+
+func (r *recorder) Start(context.Context) error {
+	if r.startErr != nil {
+		return r.startErr
+	}
+	*r.events = append(*r.events, "start "+r.name)
+	return nil
+}
+
+func (r *recorder) Stop(context.Context) error {
+	*r.events = append(*r.events, "stop "+r.name)
+	return nil
+}
+
+func TestNewWithConfigMissingName(t *testing.T) {
+	c := loadConfig(t, "app:\n  mode: testing\n")
+	if _, err := NewWithConfig(c); err == nil {
+		t.Fatal("expected error for missing app.name")
+	}
+}
+
+func TestNewWithConfigCallbacks(t *testing.T) {
+	c := loadConfig(t, "app:\n  name: test\n  mode: testing\n")
+	calls := 0
+	if _, err := NewWithConfig(c, ConfigCallbacks(func(config.IConfig) { calls++ })); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("got %d calls, want 1 with the initial config", calls)
+	}
+}
+
+func TestNewWithConfigKeepsSecrets(t *testing.T) {
+	c := loadConfig(t, `
+app:
+  name: test
+  mode: testing
+logger:
+  level: debug
+  filename: `+filepath.Join(t.TempDir(), "app.log")+`
+db:
+  replicas:
+    - password: p@ss
+`)
+	if _, err := NewWithConfig(c); err != nil {
+		t.Fatal(err)
+	}
+	var db struct {
+		Replicas []struct{ Password string }
+	}
+	if err := c.Scan("db", &db); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.Replicas) != 1 || db.Replicas[0].Password != "p@ss" {
+		t.Fatalf("the config dump changed the config: %+v", db)
+	}
+}
+
+func TestNewWithConfigInvalidTLS(t *testing.T) {
+	c := loadConfig(t, `
+app:
+  name: test
+  mode: testing
+http:
+  addr: 127.0.0.1:0
+  tls:
+    certFile: /nonexistent/tls.crt
+    keyFile: /nonexistent/tls.key
+`)
+	noop := func(*gin.Engine) error { return nil }
+	if _, err := NewWithConfig(c, InitHttpServer(noop)); err == nil {
+		t.Fatal("expected error for missing http.tls files")
+	}
+	// the tracer provider installed before the failure is shut down
+	_, span := otel.Tracer("test").Start(context.Background(), "span")
+	defer span.End()
+	if span.IsRecording() {
+		t.Fatal("tracer provider not shut down")
+	}
+}
+
+func TestNewWithConfigTLSDisabled(t *testing.T) {
+	c := loadConfig(t, `
+app:
+  name: test
+  mode: testing
+http:
+  tls:
+    certFile: /nonexistent/tls.crt
+    keyFile: /nonexistent/tls.key
+  servers:
+    internal:
+      addr: 127.0.0.1:0
+      tls:
+        disabled: true
+`)
+	noop := func(*gin.Engine) error { return nil }
+	if _, err := NewWithConfig(c, InitNamedHttpServer("internal", noop)); err != nil {
+		t.Fatalf("http.servers.internal should not use http.tls: %v", err)
+	}
+}
+
+func TestNewWithConfigMultipleServers(t *testing.T) {
+	c := loadConfig(t, `
+app:
+  name: test
+  mode: testing
+logger:
+  filename: `+filepath.Join(t.TempDir(), "app.log")+`
+http:
+  servers:
+    public:
+      addr: 127.0.0.1:0
+    internal:
+      addr: 127.0.0.1:0
+rpc:
+  servers:
+    local:
+      network: unix
+      addr: `+filepath.Join(t.TempDir(), "rpc.sock")+`
+`)
+	named := func(name string) http.InitHttpServerFunc {
+		return func(r *gin.Engine) error {
+			r.Use(func(c *gin.Context) { c.Writer.Header().Add("X-Server", name) })
+			r.GET("/", func(c *gin.Context) { c.String(200, name) })
+			return nil
+		}
+	}
+	rpcInit := false
+	app, err := NewWithConfig(c,
+		InitNamedHttpServer("public", named("public")),
+		InitNamedHttpServer("internal", named("internal")),
+		InitNamedRpcServer("local", func(*rpcxServer.Server) error {
+			rpcInit = true
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(app.httpServers) != 2 || len(app.rpcServers) != 1 {
+		t.Fatalf("got %d http and %d rpc servers, want 2 and 1", len(app.httpServers), len(app.rpcServers))
+	}
+	if app.httpServers[0].Engine == app.httpServers[1].Engine {
+		t.Fatal("the http servers share their engine")
+	}
+	if err = app.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer app.shutdown()
+
+	if !rpcInit || app.rpcServers[0].Addr().Network() != "unix" {
+		t.Fatalf("rpc.servers.local is not served on its unix socket")
+	}
+	served := map[string]bool{}
+	for _, s := range app.httpServers {
+		rsp, err := nethttp.Get("http://" + s.Addr().String() + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(rsp.Body)
+		_ = rsp.Body.Close()
+		// only the middleware of its own engine ran
+		if h := rsp.Header.Values("X-Server"); len(h) != 1 || h[0] != string(b) {
+			t.Fatalf("%s: got X-Server %v", b, h)
+		}
+		served[string(b)] = true
+	}
+	if !served["public"] || !served["internal"] {
+		t.Fatalf("got %v, want public and internal", served)
+	}
+}
+
+func TestNewWithConfigMissingServer(t *testing.T) {
+	c := loadConfig(t, `
+app:
+  name: test
+  mode: testing
+http:
+  servers:
+    public:
+      addr: 127.0.0.1:0
+`)
+	noop := func(*gin.Engine) error { return nil }
+	if _, err := NewWithConfig(c, InitNamedHttpServer("admin", noop)); err == nil {
+		t.Fatal("expected error for missing http.servers.admin")
+	}
+}
+
+func TestAdminMetrics(t *testing.T) {
+	c := loadConfig(t, `
+app:
+  name: test
+  mode: testing
+logger:
+  filename: `+filepath.Join(t.TempDir(), "app.log")+`
+http:
+  addr: 127.0.0.1:0
+admin:
+  addr: 127.0.0.1:0
+`)
+	m, err := metrics.New(metrics.Registry(prometheus.NewRegistry()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the route of the app does not conflict with the metrics
+	app, err := NewWithConfig(c, Metrics(m), InitHttpServer(func(r *gin.Engine) error {
+		r.GET("/metrics", func(c *gin.Context) { c.String(200, "app") })
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer app.shutdown()
+
+	get := func(s *http.Server) string {
+		rsp, err := nethttp.Get("http://" + s.Addr().String() + "/metrics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rsp.Body.Close()
+		b, _ := io.ReadAll(rsp.Body)
+		return string(b)
+	}
+	if got := get(app.httpServers[0]); got != "app" {
+		t.Fatalf("http server: got %q, want the route of the app", got)
+	}
+	if got := get(app.httpServers[1]); !strings.Contains(got, "http_server") {
+		t.Fatalf("admin server: got %q, want the metrics", got)
+	}
+}
+
+func TestAppLifecycle(t *testing.T) {
+	c := loadConfig(t, `
+app:
+  name: test
+  mode: testing
+logger:
+  filename: `+filepath.Join(t.TempDir(), "app.log")+`
+http:
+  addr: 127.0.0.1:0
+`)
+
+	var events []string
+	app, err := NewWithConfig(c,
+		InitHttpServer(func(r *gin.Engine) error { return nil }),
+		Components(&recorder{name: "a", events: &events}),
+		OnStop(func(context.Context) error {
+			events = append(events, "hook 1")
+			return nil
+		}),
+		After(func() error {
+			events = append(events, "hook 2")
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Add(&recorder{name: "b", events: &events})
+
+	if err = app.start(); err != nil {
+		t.Fatal(err)
+	}
+	if err = app.shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"start a", "start b", "stop b", "stop a", "hook 2", "hook 1"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("got %v, want %v", events, want)
+	}
+}
+
+// slowApp starts an app whose /slow handler runs for d, and returns it
+// with a channel closed when a request reached the handler.
+func slowApp(t *testing.T, d time.Duration, opts ...Option) (*App, <-chan struct{}) {
+	t.Helper()
+	c := loadConfig(t, `
+app:
+  name: test
+  mode: testing
+logger:
+  filename: `+filepath.Join(t.TempDir(), "app.log")+`
+http:
+  addr: 127.0.0.1:0
+`)
+	started := make(chan struct{})
+	var once sync.Once
+	app, err := NewWithConfig(c, append(opts, InitHttpServer(func(r *gin.Engine) error {
+		r.GET("/slow", func(c *gin.Context) {
+			once.Do(func() { close(started) })
+			time.Sleep(d)
+			c.String(200, "done")
+		})
+		return nil
+	}))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.start(); err != nil {
+		t.Fatal(err)
+	}
+	return app, started
+}
+
+func TestAppShutdownDrains(t *testing.T) {
+	grace := 200 * time.Millisecond
+	app, started := slowApp(t, 300*time.Millisecond, GracePeriod(grace), ShutdownTimeout(5*time.Second))
+	url := "http://" + app.httpServers[0].Addr().String() + "/slow"
+
+	type result struct {
+		body string
+		err  error
+	}
+	res := make(chan result, 1)
+	go func() {
+		rsp, err := nethttp.Get(url)
+		if err != nil {
+			res <- result{err: err}
+			return
+		}
+		defer rsp.Body.Close()
+		b, err := io.ReadAll(rsp.Body)
+		res <- result{string(b), err}
+	}()
+	<-started
+
+	begin := time.Now()
+	if err := app.shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(begin); d < grace {
+		t.Fatalf("shutdown took %v, want at least the grace period %v", d, grace)
+	}
+	if r := <-res; r.err != nil || r.body != "done" {
+		t.Fatalf("in-flight request: got %q %v, want it to finish", r.body, r.err)
+	}
+}
+
+func TestAppShutdownTimeout(t *testing.T) {
+	app, started := slowApp(t, 5*time.Second, ShutdownTimeout(100*time.Millisecond))
+	url := "http://" + app.httpServers[0].Addr().String() + "/slow"
+	go func() {
+		if rsp, err := nethttp.Get(url); err == nil {
+			_ = rsp.Body.Close()
+		}
+	}()
+	<-started
+
+	begin := time.Now()
+	err := app.shutdown()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the shutdown timeout", err)
+	}
+	if d := time.Since(begin); d > 2*time.Second {
+		t.Fatalf("shutdown took %v, the slow handler was not cut off", d)
+	}
+}
+
+// stuckWriter is a log sink whose Sync blocks, like a remote sink
+// retrying against an unreachable collector.
+type stuckWriter struct {
+	release chan struct{}
+}
+
+func (w stuckWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w stuckWriter) Sync() error {
+	<-w.release
+	return nil
+}
+
+func TestAppShutdownLogSync(t *testing.T) {
+	app, _ := slowApp(t, 0, ShutdownTimeout(100*time.Millisecond))
+	w := stuckWriter{release: make(chan struct{})}
+	defer close(w.release)
+	old := log.Default()
+	log.ResetDefault(log.NewWithSinks([]log.Sink{{Writer: w}}, log.InfoLevel))
+	defer log.ResetDefault(old)
+
+	begin := time.Now()
+	if err := app.shutdown(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the shutdown timeout", err)
+	}
+	if d := time.Since(begin); d > 2*time.Second {
+		t.Fatalf("shutdown took %v, the log sync was not cut off", d)
+	}
+}
+
+func TestAppStartRollback(t *testing.T) {
+	c := loadConfig(t, `
+app:
+  name: test
+  mode: testing
+logger:
+  filename: `+filepath.Join(t.TempDir(), "app.log")+`
+`)
+
+	var events []string
+	errStart := errors.New("boom")
+	app, err := NewWithConfig(c, Components(
+		&recorder{name: "a", events: &events},
+		&recorder{name: "b", events: &events},
+		&recorder{name: "c", events: &events, startErr: errStart},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = app.start(); !errors.Is(err, errStart) {
+		t.Fatalf("got %v, want %v", err, errStart)
+	}
+
+	want := []string{"start a", "start b", "stop b", "stop a"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("got %v, want %v", events, want)
+	}
+}
+
+func TestAppServeError(t *testing.T) {
+	c := loadConfig(t, `
+app:
+  name: test
+  mode: testing
+logger:
+  filename: `+filepath.Join(t.TempDir(), "app.log")+`
+`)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := NewWithConfig(c, InitHttpServer(func(r *gin.Engine) error { return nil }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.httpServers[0].Init(http.Listener(l))
+
+	if err = app.start(); err != nil {
+		t.Fatal(err)
+	}
+	if got := app.httpServers[0].Addr(); got.String() != l.Addr().String() {
+		t.Fatalf("Addr() = %v, want %v", got, l.Addr())
+	}
+
+	// the listener fails under the server
+	_ = l.Close()
+	select {
+	case err = <-app.serveErrors():
+		if err == nil {
+			t.Fatal("expected a serve error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve error not reported")
+	}
+	_ = app.shutdown()
+}